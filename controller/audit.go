@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/service"
+	"net/http"
+)
+
+var Audit audit
+
+type audit struct{}
+
+// List 返回审计记录列表，支持按用户、集群、资源、时间等过滤
+func (*audit) List(c *gin.Context) {
+	params := new(dao.AuditQuery)
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.Audit.List(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取审计记录列表成功",
+		"data": data,
+	})
+}
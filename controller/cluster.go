@@ -2,23 +2,164 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
 	"kubea/service"
 	"net/http"
-	"sort"
 )
 
 var Cluster cluster
 
 type cluster struct{}
 
+// GetClusters 返回集群列表及连接状态
 func (*cluster) GetClusters(c *gin.Context) {
-	list := make([]string, 0)
-	for key := range service.K8s.ClientMap {
-		list = append(list, key)
+	data, err := service.Cluster.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
-	sort.Strings(list)
+
 	c.JSON(http.StatusOK, gin.H{
 		"msg":  "获取集群信息成功",
-		"data": list,
+		"data": data,
+	})
+}
+
+// GetHealth 返回集群健康状态，cluster 为空时返回所有集群
+func (*cluster) GetHealth(c *gin.Context) {
+	params := new(struct {
+		Cluster string `form:"cluster"`
+	})
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取集群健康状态成功",
+		"data": service.K8s.Health(params.Cluster),
+	})
+}
+
+// GetCacheStatus 返回集群资源缓存的同步状态，cluster 为空时返回所有集群
+func (*cluster) GetCacheStatus(c *gin.Context) {
+	params := new(struct {
+		Cluster string `form:"cluster"`
+	})
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取集群缓存状态成功",
+		"data": service.K8s.CacheStatus(params.Cluster),
+	})
+}
+
+// Add 新增
+func (*cluster) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.Cluster)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Cluster.Add(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "新增集群成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*cluster) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.Cluster)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Cluster.Update(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "更新集群成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*cluster) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Cluster.Delete(params.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "删除集群成功",
+		"data": nil,
 	})
 }
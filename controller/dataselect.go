@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubea/service"
+)
+
+// dataSelectQuery 绑定列表接口通用的过滤、排序、分页参数
+// 支持 filter_name、name_mode、label_selector、phase、node_name、owner_kind、sort_by、limit、page
+func dataSelectQuery(c *gin.Context) (*service.DataSelectQuery, error) {
+	params := new(service.DataSelectParams)
+	if err := c.ShouldBindQuery(params); err != nil {
+		return nil, err
+	}
+	return params.Query(scopeNamespaces(c))
+}
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"io"
+	"kubea/dao"
 	"kubea/model"
 	"kubea/service"
+	"kubea/utils"
 	"net/http"
 )
 
@@ -160,10 +164,13 @@ func (*deploy) Delete(c *gin.Context) {
 	})
 }
 
-// CiCd 新增
+// CiCd 开始部署，返回本次运行记录
 func (*deploy) CiCd(c *gin.Context) {
 	//接收参数
-	params := new(model.Deploy)
+	params := new(struct {
+		model.Deploy
+		emergencyParams
+	})
 
 	//绑定参数
 	if err := c.ShouldBind(params); err != nil {
@@ -176,14 +183,10 @@ func (*deploy) CiCd(c *gin.Context) {
 		return
 	}
 
-	//调用Service方法
-	err := service.Deploy.CiCd(params)
+	//调用Service方法，发起人取自登录用户
+	data, err := service.Deploy.CiCd(&params.Deploy, username(c), params.emergency(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 90500,
-			"msg":  err.Error(),
-			"data": nil,
-		})
+		deployFailed(c, err)
 		return
 	}
 
@@ -191,11 +194,11 @@ func (*deploy) CiCd(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "构建部署任务触发",
-		"data": nil,
+		"data": data,
 	})
 }
 
-// JenkinsCiCd 新增
+// JenkinsCiCd Jenkins 发起的部署
 func (*deploy) JenkinsCiCd(c *gin.Context) {
 	//接收参数
 	params := new(struct {
@@ -203,6 +206,7 @@ func (*deploy) JenkinsCiCd(c *gin.Context) {
 		AppId     uint   `json:"app_id"`
 		StartTime string `json:"start_time" gorm:"column:start_time"`
 		Builder   string `json:"builder"`
+		BuildUrl  string `json:"build_url"`
 	})
 
 	//绑定参数
@@ -217,7 +221,44 @@ func (*deploy) JenkinsCiCd(c *gin.Context) {
 	}
 
 	//调用Service方法
-	err := service.Deploy.JenkinsCiCd(params.AppId, params.En, params.StartTime, params.Builder)
+	data, err := service.Deploy.JenkinsCiCd(params.AppId, params.En, params.StartTime, params.Builder, params.BuildUrl)
+	if err != nil {
+		deployFailed(c, err)
+		return
+	}
+
+	//返回 run ID，Jenkins 回调时使用
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "构建部署任务触发",
+		"data": data,
+	})
+}
+
+// UpdateCiCd Jenkins 回调，按 run ID 更新发布状态
+func (*deploy) UpdateCiCd(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		RunId    int64             `json:"run_id,string" binding:"required"`
+		State    model.DeployState `json:"state" binding:"required"`
+		Branch   string            `json:"branch"`
+		Message  string            `json:"message"`
+		Artifact string            `json:"artifact"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.Deploy.UpdateCiCd(params.RunId, params.State, params.Branch, params.Message, params.Artifact)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 90500,
@@ -230,26 +271,198 @@ func (*deploy) JenkinsCiCd(c *gin.Context) {
 	//返回
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
-		"msg":  "构建部署任务触发",
+		"msg":  "更新CICD流程成功",
 		"data": nil,
 	})
 }
 
-// UpdateCiCd 列表
-func (*deploy) UpdateCiCd(c *gin.Context) {
+// GetLog 查看日志，支持分页，传入 since 时增量返回之后的日志
+func (*deploy) GetLog(c *gin.Context) {
+	//接收参数
+	params := new(dao.DeployLogQuery)
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.GetLog(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取部署日志成功",
+		"data": data,
+	})
+}
+
+// Cancel 取消进行中或等待审批的发布，未指定 run_id 时取消最近一次运行
+func (*deploy) Cancel(c *gin.Context) {
 	//接收参数
 	params := new(struct {
-		En           string `json:"en"`
-		AppName      string `json:"app_name"`
-		RepoName     string `json:"repo_name"`
-		Branch       string `json:"branch"`
-		CodeCheck    int    `json:"code_check"`
-		BuildStatus  int    `json:"build_status"`
-		DeployStatus int    `json:"deploy_status"`
+		ID    uint  `json:"id"`
+		RunId int64 `json:"run_id,string"`
 	})
 
 	//绑定参数
 	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.Cancel(params.ID, params.RunId, username(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "取消发布成功",
+		"data": data,
+	})
+}
+
+// Rerun 重新发布，run_id 为空时沿用最近一次发布的分支和 tag
+func (*deploy) Rerun(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID    uint  `json:"id"`
+		RunId int64 `json:"run_id,string"`
+		emergencyParams
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.Rerun(params.ID, params.RunId, username(c), params.emergency(c))
+	if err != nil {
+		deployFailed(c, err)
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "重新发布任务触发",
+		"data": data,
+	})
+}
+
+// StreamLog 通过 SSE 实时查看发布日志，先推送 since 之后的已有日志，构建结束后推送 end 事件
+// EventSource 无法设置请求头，token 通过 ?token= 传递
+func (*deploy) StreamLog(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID    uint `form:"id"`
+		Since uint `form:"since"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//先订阅再读取已有日志，避免遗漏
+	ch, active := service.Deploy.SubscribeLog(params.ID)
+	defer service.Deploy.UnsubscribeLog(params.ID, ch)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	send := func(item *model.DeployLog) {
+		c.SSEvent("log", item)
+	}
+	cursor, err := service.Deploy.LogsSince(params.ID, params.Since, send)
+	if err != nil && cursor != params.Since {
+		c.SSEvent("error", err.Error())
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	if !active {
+		c.SSEvent("end", cursor)
+		return
+	}
+
+	//收到新日志的通知后，从数据库读取 cursor 之后的日志，通知丢失时也不会遗漏
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case _, ok := <-ch:
+			next, err := service.Deploy.LogsSince(params.ID, cursor, send)
+			if err != nil {
+				c.SSEvent("error", err.Error())
+				return false
+			}
+			cursor = next
+			if !ok {
+				c.SSEvent("end", cursor)
+				return false
+			}
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// ListRuns 发布的运行记录
+func (*deploy) ListRuns(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		DeployId uint `form:"deploy_id"`
+		Page     int  `form:"page"`
+		Limit    int  `form:"limit"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
 		zap.L().Error("Bind请求参数失败," + err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code": 90400,
@@ -260,7 +473,7 @@ func (*deploy) UpdateCiCd(c *gin.Context) {
 	}
 
 	//调用Service方法
-	err := service.Deploy.UpdateCiCd(params.En, params.AppName, params.RepoName, params.Branch, params.CodeCheck, params.BuildStatus, params.DeployStatus)
+	data, err := service.DeployRun.List(params.DeployId, params.Page, params.Limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 90500,
@@ -273,16 +486,16 @@ func (*deploy) UpdateCiCd(c *gin.Context) {
 	//返回
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
-		"msg":  "更新CICD流程成功",
-		"data": nil,
+		"msg":  "获取发布记录成功",
+		"data": data,
 	})
 }
 
-// GetLog 查看日志
-func (*deploy) GetLog(c *gin.Context) {
+// GetRun 查询单次运行
+func (*deploy) GetRun(c *gin.Context) {
 	//接收参数
 	params := new(struct {
-		ID uint `form:"id"`
+		ID int64 `form:"id"`
 	})
 
 	//绑定参数
@@ -297,7 +510,7 @@ func (*deploy) GetLog(c *gin.Context) {
 	}
 
 	//调用Service方法
-	data, _, err := service.Deploy.GetLog(params.ID)
+	data, err := service.DeployRun.Get(params.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 90500,
@@ -310,7 +523,189 @@ func (*deploy) GetLog(c *gin.Context) {
 	//返回
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
-		"msg":  "删除部署信息成功",
+		"msg":  "获取发布记录成功",
+		"data": data,
+	})
+}
+
+// Promote 将发布成功的运行晋级到下一个环境，to 为空时取晋级链中的下一个环境
+func (*deploy) Promote(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		RunId int64  `json:"run_id,string" binding:"required"`
+		To    string `json:"to"`
+		emergencyParams
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.Promote(params.RunId, params.To, username(c), params.emergency(c))
+	if err != nil {
+		deployFailed(c, err)
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "晋级发布成功",
+		"data": data,
+	})
+}
+
+// Approve 审批通过
+func (*deploy) Approve(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		RunId int64 `json:"run_id,string" binding:"required"`
+		emergencyParams
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.Approve(params.RunId, username(c), roleID(c), params.emergency(c))
+	if err != nil {
+		deployFailed(c, err)
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "审批通过",
+		"data": data,
+	})
+}
+
+// Reject 驳回
+func (*deploy) Reject(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		RunId  int64  `json:"run_id,string" binding:"required"`
+		Reason string `json:"reason"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Deploy.Reject(params.RunId, username(c), roleID(c), params.Reason)
+	if err != nil {
+		deployFailed(c, err)
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "已驳回",
+		"data": data,
+	})
+}
+
+// Approvals 等待审批的运行
+func (*deploy) Approvals(c *gin.Context) {
+	data, err := service.DeployRun.Pending()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取待审批发布成功",
 		"data": data,
 	})
 }
+
+// emergencyParams 紧急发布参数，冻结窗口内发布时填写
+type emergencyParams struct {
+	Emergency       bool   `json:"emergency" form:"emergency"`
+	EmergencyReason string `json:"emergency_reason" form:"emergency_reason"`
+}
+
+// emergency 未申请紧急发布时返回 nil
+func (p *emergencyParams) emergency(c *gin.Context) *service.Emergency {
+	if !p.Emergency {
+		return nil
+	}
+	return &service.Emergency{
+		RoleID: roleID(c),
+		Reason: p.EmergencyReason,
+	}
+}
+
+// deployFailed 发布失败，被锁或处于冻结窗口时返回 409 及原因，便于前端及 Jenkins 区分，无权审批时返回 403
+func deployFailed(c *gin.Context, err error) {
+	var blocked *service.DeployBlockedError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"code": 90409,
+			"msg":  blocked.Msg,
+			"data": blocked,
+		})
+		return
+	}
+	var forbidden *service.DeployForbiddenError
+	if errors.As(err, &forbidden) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": 90403,
+			"msg":  forbidden.Msg,
+			"data": nil,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code": 90500,
+		"msg":  err.Error(),
+		"data": nil,
+	})
+}
+
+// username 当前登录用户名，webhook 接口为空
+func username(c *gin.Context) string {
+	if value, exists := c.Get("claims"); exists {
+		return value.(*utils.CustomClaims).Username
+	}
+	return ""
+}
+
+// roleID 当前登录用户的角色，webhook 接口为 0
+func roleID(c *gin.Context) uint {
+	if value, exists := c.Get("claims"); exists {
+		return value.(*utils.CustomClaims).Role
+	}
+	return 0
+}
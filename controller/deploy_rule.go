@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
+	"kubea/service"
+	"net/http"
+)
+
+var DeployRule deployRule
+
+type deployRule struct{}
+
+// List 自动发布规则列表
+func (*deployRule) List(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		AppId uint `form:"app_id"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.DeployRule.List(params.AppId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取发布规则成功",
+		"data": data,
+	})
+}
+
+// Add 新增
+func (*deployRule) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.DeployRule)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.DeployRule.Add(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "新增发布规则成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*deployRule) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.DeployRule)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.DeployRule.Update(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "更新发布规则成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*deployRule) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.DeployRule.Delete(params.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "删除发布规则成功",
+		"data": nil,
+	})
+}
@@ -14,14 +14,11 @@ type deployment struct{}
 
 // GetDeployments 获取 Deployment 列表
 func (p *deployment) GetDeployments(c *gin.Context) {
-	//client *kubernetes.Clientset, filterName, namespace string, limit, page int
+	//client *kubernetes.Clientset, namespace string, query *DataSelectQuery
 	//接收参数,匿名结构体，get请求为form格式，其他请求为json格式
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Limit      int    `form:"limit"`
-		Page       int    `form:"page"`
-		Cluster    string `form:"cluster"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
 	})
 
 	//绑定参数
@@ -35,6 +32,16 @@ func (p *deployment) GetDeployments(c *gin.Context) {
 		return
 	}
 
+	//绑定过滤、排序、分页参数
+	query, err := dataSelectQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	//获取client
 	client, err := service.K8s.GetClient(params.Cluster)
 	if err != nil {
@@ -46,7 +53,7 @@ func (p *deployment) GetDeployments(c *gin.Context) {
 	}
 
 	//调用service方法，获取列表
-	data, err := service.Deployment.GetDeployments(client, params.FilterName, params.Namespace, params.Limit, params.Page)
+	data, err := service.Deployment.GetDeployments(client, params.Namespace, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -146,3 +146,54 @@ func (*env) Delete(c *gin.Context) {
 		"data": nil,
 	})
 }
+
+// Chain 晋级链
+func (*env) Chain(c *gin.Context) {
+	data, err := service.Env.Chain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "获取晋级链成功",
+		"data": data,
+	})
+}
+
+// Policy 更新晋级顺序、是否需要审批及审批角色
+func (*env) Policy(c *gin.Context) {
+	params := new(model.Env)
+
+	// 绑定请求参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	err := service.Env.Policy(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "更新环境晋级策略成功",
+		"data": nil,
+	})
+}
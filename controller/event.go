@@ -27,7 +27,7 @@ func (*event) GetList(ctx *gin.Context) {
 		})
 		return
 	}
-	data, err := service.Event.GetList(params.Name, params.Cluster, params.Page, params.Limit)
+	data, err := service.Event.GetList(params.Name, params.Cluster, scopeNamespaces(ctx), params.Page, params.Limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
+	"kubea/service"
+	"net/http"
+	"time"
+)
+
+var Freeze freeze
+
+type freeze struct{}
+
+// List 冻结窗口列表
+func (*freeze) List(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		En string `form:"en"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	data, err := service.Freeze.List(params.En)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取冻结窗口成功",
+		"data": data,
+	})
+}
+
+// Add 新增
+func (*freeze) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.FreezeWindow)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Freeze.Add(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "新增冻结窗口成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*freeze) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.FreezeWindow)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Freeze.Update(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "更新冻结窗口成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*freeze) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.Freeze.Delete(params.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "删除冻结窗口成功",
+		"data": nil,
+	})
+}
+
+// Check 查询环境当前是否处于冻结窗口
+func (*freeze) Check(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		En string `form:"en" binding:"required"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	window, until, err := service.Freeze.Active(params.En, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "查询冻结窗口成功",
+		"data": gin.H{
+			"frozen": window != nil,
+			"window": window,
+			"until":  until,
+		},
+	})
+}
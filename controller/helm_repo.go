@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
+	"kubea/service"
+	"net/http"
+)
+
+var HelmRepo helmRepo
+
+type helmRepo struct{}
+
+// List 返回远程仓库列表
+func (*helmRepo) List(c *gin.Context) {
+	data, err := service.HelmRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取仓库列表成功",
+		"data": data,
+	})
+}
+
+// Add 新增
+func (*helmRepo) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.HelmRepo)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.HelmRepo.Add(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "新增仓库成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*helmRepo) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.HelmRepo)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.HelmRepo.Update(params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "更新仓库成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*helmRepo) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	if err := service.HelmRepo.Delete(params.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "删除仓库成功",
+		"data": nil,
+	})
+}
+
+// Refresh 刷新仓库 index
+func (*helmRepo) Refresh(c *gin.Context) {
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	if err := service.HelmRepo.Refresh(params.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "刷新仓库成功",
+		"data": nil,
+	})
+}
+
+// Search 在远程仓库中搜索 chart
+func (*helmRepo) Search(c *gin.Context) {
+	params := new(struct {
+		Keyword  string `form:"keyword"`
+		Repo     string `form:"repo"`
+		Versions bool   `form:"versions"`
+	})
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.HelmRepo.Search(params.Keyword, params.Repo, params.Versions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "搜索Chart成功",
+		"data": data,
+	})
+}
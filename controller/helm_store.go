@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"io"
 	"kubea/model"
 	"kubea/service"
+	"mime/multipart"
 	"net/http"
 )
 
@@ -12,12 +16,15 @@ var HelmStore helmStore
 
 type helmStore struct{}
 
+// chartFormOverhead 上传chart时 multipart 表单除文件外的开销上限
+const chartFormOverhead = 1 << 20
+
 // ListReleases 已安装的release列表
 func (*helmStore) ListReleases(ctx *gin.Context) {
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Cluster    string `form:"cluster"`
+		service.ReleaseQuery
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
 	})
 	if err := ctx.Bind(params); err != nil {
 		zap.L().Error("Bind请求参数失败, " + err.Error())
@@ -27,6 +34,7 @@ func (*helmStore) ListReleases(ctx *gin.Context) {
 		})
 		return
 	}
+	// namespace 为空时列出集群所有名称空间，由 ClusterScope 限制可访问的名称空间
 	actionConfig, err := service.HelmConfig.GetAction(params.Cluster, params.Namespace)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -35,7 +43,7 @@ func (*helmStore) ListReleases(ctx *gin.Context) {
 		})
 		return
 	}
-	data, err := service.HelmStore.ListReleases(actionConfig, params.FilterName)
+	data, err := service.HelmStore.ListReleases(actionConfig, &params.ReleaseQuery, scopeNamespaces(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -90,9 +98,138 @@ func (*helmStore) DetailRelease(ctx *gin.Context) {
 
 // InstallRelease release安装
 func (*helmStore) InstallRelease(ctx *gin.Context) {
+	params := new(struct {
+		Release   string   `json:"release"`
+		Chart     string   `json:"chart"`
+		Version   string   `json:"version"`
+		Values    string   `json:"values"`
+		Set       []string `json:"set"`
+		DryRun    bool     `json:"dry_run"`
+		Namespace string   `json:"namespace"`
+		Cluster   string   `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	actionConfig, err := service.HelmConfig.GetAction(params.Cluster, params.Namespace)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.HelmStore.InstallRelease(actionConfig, params.Release, params.Chart, params.Version, params.Namespace, params.Values, params.Set, params.DryRun)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	msg := "安装Release成功"
+	if params.DryRun {
+		msg = "渲染Release成功"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  msg,
+		"data": data,
+	})
+}
+
+// UpgradeRelease release升级
+func (*helmStore) UpgradeRelease(ctx *gin.Context) {
+	params := new(struct {
+		Release     string   `json:"release"`
+		Chart       string   `json:"chart"`
+		Version     string   `json:"version"`
+		Values      string   `json:"values"`
+		Set         []string `json:"set"`
+		ReuseValues bool     `json:"reuse_values"`
+		Namespace   string   `json:"namespace"`
+		Cluster     string   `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	actionConfig, err := service.HelmConfig.GetAction(params.Cluster, params.Namespace)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.HelmStore.UpgradeRelease(actionConfig, params.Release, params.Chart, params.Version, params.Namespace, params.Values, params.Set, params.ReuseValues)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "升级Release成功",
+		"data": data,
+	})
+}
+
+// HistoryRelease release历史版本
+func (*helmStore) HistoryRelease(ctx *gin.Context) {
+	params := new(struct {
+		Release   string `form:"release"`
+		Max       int    `form:"max"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	actionConfig, err := service.HelmConfig.GetAction(params.Cluster, params.Namespace)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.HelmStore.HistoryRelease(actionConfig, params.Release, params.Max)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取Release历史版本成功",
+		"data": data,
+	})
+}
+
+// RollbackRelease release回滚
+func (*helmStore) RollbackRelease(ctx *gin.Context) {
 	params := new(struct {
 		Release   string `json:"release"`
-		Chart     string `json:"chart"`
+		Revision  int    `json:"revision"`
 		Namespace string `json:"namespace"`
 		Cluster   string `json:"cluster"`
 	})
@@ -112,8 +249,7 @@ func (*helmStore) InstallRelease(ctx *gin.Context) {
 		})
 		return
 	}
-	err = service.HelmStore.InstallRelease(actionConfig, params.Release, params.Chart, params.Namespace)
-	if err != nil {
+	if err := service.HelmStore.RollbackRelease(actionConfig, params.Release, params.Revision); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
@@ -122,11 +258,52 @@ func (*helmStore) InstallRelease(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "安装Release成功",
+		"msg":  "回滚Release成功",
 		"data": nil,
 	})
 }
 
+// DiffRelease 对比release两个版本的values
+func (*helmStore) DiffRelease(ctx *gin.Context) {
+	params := new(struct {
+		Release   string `form:"release"`
+		From      int    `form:"from"`
+		To        int    `form:"to"`
+		All       bool   `form:"all"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	actionConfig, err := service.HelmConfig.GetAction(params.Cluster, params.Namespace)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.HelmStore.DiffReleaseValues(actionConfig, params.Release, params.From, params.To, params.All)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取Release版本差异成功",
+		"data": data,
+	})
+}
+
 // UninstallRelease release卸载
 func (*helmStore) UninstallRelease(ctx *gin.Context) {
 	params := new(struct {
@@ -166,9 +343,10 @@ func (*helmStore) UninstallRelease(ctx *gin.Context) {
 }
 
 // UploadChartFile chart文件上传
+// 请求体限制在文件大小上限加上表单开销以内，通过 MultipartReader 流式读取，不在内存或临时目录缓存整个请求
 func (*helmStore) UploadChartFile(ctx *gin.Context) {
-	//ctx.Request.FormFile可以直接获取到file multipart.File, header *multipart.FileHeader
-	file, header, err := ctx.Request.FormFile("chart")
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.ChartMaxSize()+chartFormOverhead)
+	reader, err := ctx.Request.MultipartReader()
 	if err != nil {
 		zap.L().Error("获取上传信息失败, " + err.Error())
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -177,9 +355,42 @@ func (*helmStore) UploadChartFile(ctx *gin.Context) {
 		})
 		return
 	}
-	err = service.HelmStore.UploadChartFile(file, header)
+
+	//跳过其他表单字段，找到 chart 文件
+	var part *multipart.Part
+	for {
+		if part, err = reader.NextPart(); err != nil {
+			break
+		}
+		if part.FormName() == "chart" && part.FileName() != "" {
+			break
+		}
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"msg":  fmt.Sprintf("chart文件不能超过 %dMB", service.ChartMaxSize()>>20),
+			"data": nil,
+		})
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		msg := err.Error()
+		if err == io.EOF {
+			msg = "未上传chart文件"
+		}
+		zap.L().Error("获取上传信息失败, " + msg)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  msg,
+			"data": nil,
+		})
+		return
+	}
+	defer part.Close()
+
+	data, err := service.HelmStore.UploadChartFile(part.FileName(), part)
+	if err != nil {
+		ctx.JSON(chartStatus(err), gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
@@ -188,7 +399,7 @@ func (*helmStore) UploadChartFile(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"msg":  "上传Chart文件成功",
-		"data": nil,
+		"data": data,
 	})
 }
 
@@ -250,6 +461,35 @@ func (*helmStore) ListCharts(ctx *gin.Context) {
 	})
 }
 
+// ChartValues chart默认的values.yaml和values.schema.json
+func (*helmStore) ChartValues(ctx *gin.Context) {
+	params := new(struct {
+		Chart   string `form:"chart"`
+		Version string `form:"version"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.HelmStore.ChartValues(params.Chart, params.Version)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取Chart默认values成功",
+		"data": data,
+	})
+}
+
 // AddChart chart新增
 func (*helmStore) AddChart(ctx *gin.Context) {
 	params := new(model.Chart)
@@ -263,7 +503,7 @@ func (*helmStore) AddChart(ctx *gin.Context) {
 	}
 	err := service.HelmStore.AddChart(params)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(chartStatus(err), gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
@@ -289,7 +529,7 @@ func (*helmStore) UpdateChart(ctx *gin.Context) {
 	}
 	err := service.HelmStore.UpdateChart(params)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(chartStatus(err), gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
@@ -327,3 +567,36 @@ func (*helmStore) DeleteChart(ctx *gin.Context) {
 		"data": nil,
 	})
 }
+
+// RepoFile 内置chart仓库，提供 index.yaml 及chart文件下载
+func (*helmStore) RepoFile(ctx *gin.Context) {
+	file := ctx.Param("file")
+	if file == "index.yaml" {
+		data, err := service.HelmStore.RepoIndex()
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.Data(http.StatusOK, "application/x-yaml", data)
+		return
+	}
+
+	path, err := service.HelmStore.RepoFile(file)
+	if err != nil {
+		ctx.String(http.StatusNotFound, err.Error())
+		return
+	}
+	ctx.FileAttachment(path, file)
+}
+
+// chartStatus chart 校验未通过或版本已存在时返回 400，超过大小上限时返回 413
+func chartStatus(err error) int {
+	var rejected *service.ChartRejectedError
+	if !errors.As(err, &rejected) {
+		return http.StatusInternalServerError
+	}
+	if rejected.TooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
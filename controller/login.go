@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"kubea/service"
+	"kubea/utils"
 	"net/http"
 )
 
@@ -45,9 +46,83 @@ func (*login) Auth(c *gin.Context) {
 		return
 	}
 
+	// 签发token
+	token, err := service.Login.Token(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":           "登录成功",
+		"data":          router,
+		"role":          data.Role,
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"expires_at":    token.ExpiresAt,
+	})
+}
+
+// Refresh 使用refresh token换取新的token
+func (*login) Refresh(c *gin.Context) {
+	params := new(struct {
+		RefreshToken string `json:"refresh_token"`
+	})
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("Bind请求参数失败, " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.Login.Refresh(params.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "刷新Token成功",
+		"data": data,
+	})
+}
+
+// Logout 退出登录，注销token
+func (*login) Logout(c *gin.Context) {
+	params := new(struct {
+		RefreshToken string `json:"refresh_token"`
+	})
+	// 请求体可为空，仅注销当前的access token
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(params); err != nil {
+			zap.L().Error("Bind请求参数失败, " + err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			return
+		}
+	}
+
+	claims := c.MustGet("claims").(*utils.CustomClaims)
+	if err := service.Login.Logout(claims, params.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"msg":  "登录成功",
-		"data": router,
-		"role": data.Role,
+		"msg":  "退出登录成功",
+		"data": nil,
 	})
 }
@@ -15,10 +15,7 @@ type namespace struct{}
 // GetNamespaces 获取 Namespace 列表
 func (n *namespace) GetNamespaces(c *gin.Context) {
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Limit      int    `form:"limit"`
-		Page       int    `form:"page"`
-		Cluster    string `form:"cluster"`
+		Cluster string `form:"cluster"`
 	})
 
 	if err := c.Bind(params); err != nil {
@@ -30,6 +27,16 @@ func (n *namespace) GetNamespaces(c *gin.Context) {
 		return
 	}
 
+	//绑定过滤、排序、分页参数
+	query, err := dataSelectQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	client, err := service.K8s.GetClient(params.Cluster)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -39,7 +46,7 @@ func (n *namespace) GetNamespaces(c *gin.Context) {
 		return
 	}
 
-	data, err := service.Namespace.GetNamespaces(client, params.FilterName, params.Limit, params.Page)
+	data, err := service.Namespace.GetNamespaces(client, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
+	"kubea/service"
+	"net/http"
+)
+
+var ApiPermission apiPermission
+
+type apiPermission struct{}
+
+// List 返回接口权限列表
+func (*apiPermission) List(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page"`
+		Limit int    `form:"limit"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.ApiPermission.List(params.Name, params.Page, params.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "获取接口权限列表成功",
+		"data": data,
+	})
+}
+
+// GetAll 所有接口权限，角色绑定权限时使用
+func (*apiPermission) GetAll(c *gin.Context) {
+	data, err := service.ApiPermission.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取所有接口权限成功",
+		"data": data,
+	})
+}
+
+// Add 新增
+func (*apiPermission) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.Permission)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.ApiPermission.Add(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "新增接口权限成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*apiPermission) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.Permission)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.ApiPermission.Update(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "更新接口权限成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*apiPermission) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.ApiPermission.Delete(params.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "删除接口权限成功",
+		"data": nil,
+	})
+}
+
+// GetRole 查询角色绑定的接口权限
+func (*apiPermission) GetRole(c *gin.Context) {
+	params := new(struct {
+		RoleID uint `form:"role_id"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.ApiPermission.GetRole(params.RoleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "获取角色接口权限成功",
+		"data": data,
+	})
+}
+
+// UpdateRole 更新角色绑定的接口权限
+func (*apiPermission) UpdateRole(c *gin.Context) {
+	params := new(struct {
+		RoleID        uint   `json:"role_id"`
+		PermissionIDs []uint `json:"permission_ids"`
+	})
+
+	// 绑定请求参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	err := service.ApiPermission.UpdateRole(params.RoleID, params.PermissionIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "更新角色接口权限成功",
+		"data": nil,
+	})
+}
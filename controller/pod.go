@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"io"
 	"kubea/service"
 	"net/http"
 )
@@ -14,14 +15,11 @@ type pod struct{}
 
 // GetPods 获取pod列表
 func (p *pod) GetPods(c *gin.Context) {
-	//client *kubernetes.Clientset, filterName, namespace string, limit, page int
+	//client *kubernetes.Clientset, namespace string, query *DataSelectQuery
 	//接收参数,匿名结构体，get请求为form格式，其他请求为json格式
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Limit      int    `form:"limit"`
-		Page       int    `form:"page"`
-		Cluster    string `form:"cluster"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
 	})
 
 	//绑定参数
@@ -35,6 +33,16 @@ func (p *pod) GetPods(c *gin.Context) {
 		return
 	}
 
+	//绑定过滤、排序、分页参数
+	query, err := dataSelectQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	//获取client
 	client, err := service.K8s.GetClient(params.Cluster)
 	if err != nil {
@@ -46,7 +54,7 @@ func (p *pod) GetPods(c *gin.Context) {
 	}
 
 	//调用service方法，获取列表
-	data, err := service.Pod.GetPods(client, params.FilterName, params.Namespace, params.Limit, params.Page)
+	data, err := service.Pod.GetPods(client, params.Namespace, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -296,3 +304,56 @@ func (p *pod) GetPodLog(c *gin.Context) {
 	})
 
 }
+
+// StreamPodLog 通过 SSE 实时查看容器日志，支持 follow、since_seconds/since_time、previous、timestamps 及 grep 过滤
+// 日志结束推送 end 事件，读取出错推送 error 事件，EventSource 无法设置请求头，token 通过 ?token= 传递
+func (p *pod) StreamPodLog(c *gin.Context) {
+	//接收参数
+	params := new(service.PodLogQuery)
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error(fmt.Sprintf("绑定参数失败， %v\n", err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  fmt.Sprintf("绑定参数失败， %v\n", err),
+			"data": nil,
+		})
+		return
+	}
+
+	//获取client
+	client, err := service.K8s.GetClient(params.Cluster)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//打开日志流，客户端断开时随请求取消
+	stream, err := service.Pod.OpenLog(c.Request.Context(), client, params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		line, err := stream.Next()
+		if err != nil {
+			if err != io.EOF && c.Request.Context().Err() == nil {
+				c.SSEvent("error", err.Error())
+			}
+			c.SSEvent("end", "")
+			return false
+		}
+		c.SSEvent("log", line)
+		return true
+	})
+}
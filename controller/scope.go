@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/model"
+	"kubea/service"
+	"net/http"
+)
+
+var Scope scope
+
+type scope struct{}
+
+// List 返回名称空间权限列表
+func (*scope) List(c *gin.Context) {
+	params := new(struct {
+		RoleID  uint   `form:"role_id"`
+		Cluster string `form:"cluster"`
+		Page    int    `form:"page"`
+		Limit   int    `form:"limit"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.Scope.List(params.RoleID, params.Cluster, params.Page, params.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 90200,
+		"msg":  "获取名称空间权限列表成功",
+		"data": data,
+	})
+}
+
+// Add 新增
+func (*scope) Add(c *gin.Context) {
+	//接收参数
+	params := new(model.RoleScope)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.Scope.Add(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "新增名称空间权限成功",
+		"data": nil,
+	})
+}
+
+// Update 更新
+func (*scope) Update(c *gin.Context) {
+	//接收参数
+	params := new(model.RoleScope)
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.Scope.Update(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "更新名称空间权限成功",
+		"data": nil,
+	})
+}
+
+// Delete 删除
+func (*scope) Delete(c *gin.Context) {
+	//接收参数
+	params := new(struct {
+		ID uint `json:"id"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBind(params); err != nil {
+		zap.L().Error("ShouldBind请求参数失败," + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//调用Service方法
+	err := service.Scope.Delete(params.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	//返回
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "删除名称空间权限成功",
+		"data": nil,
+	})
+}
+
+// scopeNamespaces 获取 middle.ClusterScope 写入的可访问名称空间，nil 表示不限制
+func scopeNamespaces(c *gin.Context) []string {
+	value, exists := c.Get("namespaces")
+	if !exists {
+		return nil
+	}
+	return value.([]string)
+}
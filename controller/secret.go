@@ -15,11 +15,8 @@ type secret struct{}
 // GetSecrets 获取 Secret 列表
 func (p *secret) GetSecrets(c *gin.Context) {
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Limit      int    `form:"limit"`
-		Page       int    `form:"page"`
-		Cluster    string `form:"cluster"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
 	})
 
 	if err := c.Bind(params); err != nil {
@@ -31,6 +28,16 @@ func (p *secret) GetSecrets(c *gin.Context) {
 		return
 	}
 
+	//绑定过滤、排序、分页参数
+	query, err := dataSelectQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	//获取client
 	client, err := service.K8s.GetClient(params.Cluster)
 	if err != nil {
@@ -42,7 +49,7 @@ func (p *secret) GetSecrets(c *gin.Context) {
 	}
 
 	//调用service方法，
-	data, err := service.Secret.GetSecrets(client, params.FilterName, params.Namespace, params.Limit, params.Page)
+	data, err := service.Secret.GetSecrets(client, params.Namespace, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/service"
+	"net/http"
+)
+
+var Terminal terminal
+
+type terminal struct{}
+
+// Records 返回终端会话记录，支持按用户、集群、名称空间、pod、时间过滤
+func (*terminal) Records(c *gin.Context) {
+	params := new(dao.TerminalQuery)
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.Terminal.Records(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取终端会话记录成功",
+		"data": data,
+	})
+}
+
+// Replay 返回 asciinema 录像文件，供 asciinema-player 回放
+func (*terminal) Replay(c *gin.Context) {
+	params := new(struct {
+		ID int64 `form:"id" binding:"required"`
+	})
+
+	//绑定参数
+	if err := c.Bind(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, path, err := service.Terminal.RecordFile(params.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-asciicast")
+	c.FileAttachment(path, data.FileName)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"kubea/service"
+	"net/http"
+)
+
+var Webhook webhook
+
+type webhook struct{}
+
+// List 返回所有集成及密钥
+func (*webhook) List(c *gin.Context) {
+	data, err := service.Webhook.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "获取Webhook列表成功",
+		"data": data,
+	})
+}
+
+// Update 启用、停用集成
+func (*webhook) Update(c *gin.Context) {
+	params := new(struct {
+		Name        string `json:"name" binding:"required"`
+		Enabled     bool   `json:"enabled"`
+		Description string `json:"description"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBindJSON(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	if err := service.Webhook.Update(params.Name, params.Enabled, params.Description); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "更新Webhook成功",
+		"data": nil,
+	})
+}
+
+// Rotate 轮换密钥，grace 为旧密钥继续有效的秒数，默认 3600，0 表示立即失效
+// 新密钥只在此接口返回一次
+func (*webhook) Rotate(c *gin.Context) {
+	params := new(struct {
+		Name  string `json:"name" binding:"required"`
+		Grace *int   `json:"grace"`
+	})
+
+	//绑定参数
+	if err := c.ShouldBindJSON(params); err != nil {
+		zap.L().Error("Bind 请求参数失败：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	grace := 3600
+	if params.Grace != nil {
+		grace = *params.Grace
+	}
+	data, secret, err := service.Webhook.Rotate(params.Name, grace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "轮换Webhook密钥成功，密钥只显示一次，请妥善保存",
+		"data": gin.H{
+			"webhook": data,
+			"secret":  secret,
+		},
+	})
+}
+
+// Gitlab 接收 GitLab push、tag push、merge request 事件，按规则自动发布，匹配后立即返回，发布异步执行
+func (*webhook) Gitlab(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	data, err := service.GitLab.Hook(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":  "已接收GitLab事件，匹配的规则将异步发布",
+		"data": data,
+	})
+}
@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+	"time"
+)
+
+var Audit audit
+
+type audit struct{}
+
+type AuditLogs struct {
+	Items []*model.AuditLog `json:"items"`
+	Total int               `json:"total"`
+}
+
+// AuditQuery 审计记录的过滤条件，零值不过滤
+type AuditQuery struct {
+	UserName  string    `form:"user_name"`
+	Method    string    `form:"method"`
+	Path      string    `form:"path"`
+	Cluster   string    `form:"cluster"`
+	Namespace string    `form:"namespace"`
+	Resource  string    `form:"resource"`
+	Status    int       `form:"status"`
+	StartTime time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
+	EndTime   time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
+	Page      int       `form:"page"`
+	Limit     int       `form:"limit"`
+}
+
+// List 列表
+func (*audit) List(q *AuditQuery) (*AuditLogs, error) {
+	//计算分页
+	startSet := (q.Page - 1) * q.Limit
+
+	//定义返回值的内容
+	var (
+		auditList = make([]*model.AuditLog, 0)
+		total     = 0
+	)
+
+	query := db.GORM.Model(&model.AuditLog{})
+	if q.UserName != "" {
+		query = query.Where("user_name = ?", q.UserName)
+	}
+	if q.Method != "" {
+		query = query.Where("method = ?", q.Method)
+	}
+	if q.Path != "" {
+		query = query.Where("path like ?", "%"+q.Path+"%")
+	}
+	if q.Cluster != "" {
+		query = query.Where("cluster = ?", q.Cluster)
+	}
+	if q.Namespace != "" {
+		query = query.Where("namespace = ?", q.Namespace)
+	}
+	if q.Resource != "" {
+		query = query.Where("resource = ?", q.Resource)
+	}
+	if q.Status != 0 {
+		query = query.Where("status = ?", q.Status)
+	}
+	if !q.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", q.EndTime)
+	}
+
+	tx := query.Count(&total)
+	if tx.Error != nil {
+		zap.L().Error("获取AuditLog列表失败," + tx.Error.Error())
+		return nil, errors.New("获取AuditLog列表失败," + tx.Error.Error())
+	}
+
+	//分页数据
+	tx = query.Limit(q.Limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&auditList)
+	if tx.Error != nil {
+		zap.L().Error("获取AuditLog列表失败," + tx.Error.Error())
+		return nil, errors.New("获取AuditLog列表失败," + tx.Error.Error())
+	}
+
+	return &AuditLogs{
+		Items: auditList,
+		Total: total,
+	}, nil
+}
+
+// Add 新增
+func (*audit) Add(a *model.AuditLog) error {
+	tx := db.GORM.Create(&a)
+	if tx.Error != nil {
+		zap.L().Error("新增AuditLog失败," + tx.Error.Error())
+		return errors.New("新增AuditLog失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Clean 清理指定时间之前的审计记录
+func (*audit) Clean(before time.Time) (int64, error) {
+	tx := db.GORM.Where("created_at < ?", before).Delete(&model.AuditLog{})
+	if tx.Error != nil {
+		zap.L().Error("清理AuditLog失败," + tx.Error.Error())
+		return 0, errors.New("清理AuditLog失败," + tx.Error.Error())
+	}
+
+	return tx.RowsAffected, nil
+}
@@ -13,6 +13,9 @@ var Chart chart
 
 type chart struct{}
 
+// ErrChartExists Name + Version 唯一索引冲突
+var ErrChartExists = errors.New("该Chart版本已存在")
+
 type Charts struct {
 	Items []*model.Chart `json:"items"`
 	Total int            `json:"total"`
@@ -63,9 +66,70 @@ func (*chart) Has(name string) (*model.Chart, bool, error) {
 	return data, true, nil
 }
 
+// Get 根据ID查询
+func (*chart) Get(id uint) (*model.Chart, bool, error) {
+	data := &model.Chart{}
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if tx.Error != nil {
+		zap.L().Error(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+		return nil, false, errors.New(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+	}
+	return data, true, nil
+}
+
+// HasVersion 根据名称和版本查询
+func (*chart) HasVersion(name, version string) (*model.Chart, bool, error) {
+	data := &model.Chart{}
+	tx := db.GORM.Where("name = ? and version = ?", name, version).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if tx.Error != nil {
+		zap.L().Error(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+		return nil, false, errors.New(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+	}
+	return data, true, nil
+}
+
+// HasFile 根据文件名查询
+// file_name 的列名由 tag 生成，使用结构体作为条件，避免手写列名
+func (*chart) HasFile(fileName string) (*model.Chart, bool, error) {
+	data := &model.Chart{}
+	tx := db.GORM.Where(&model.Chart{FileName: fileName}).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if tx.Error != nil {
+		zap.L().Error(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+		return nil, false, errors.New(fmt.Sprintf("查询Chart失败, %v\n", tx.Error))
+	}
+	return data, true, nil
+}
+
+// GetVersions 查询chart的所有版本，name 为空时查询所有chart
+func (*chart) GetVersions(name string) ([]*model.Chart, error) {
+	data := make([]*model.Chart, 0)
+	query := db.GORM.Model(&model.Chart{})
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	tx := query.Order("name, id desc").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error(fmt.Sprintf("查询Chart版本失败, %v\n", tx.Error))
+		return nil, errors.New(fmt.Sprintf("查询Chart版本失败, %v\n", tx.Error))
+	}
+	return data, nil
+}
+
 // Add 新增
 func (*chart) Add(chart *model.Chart) error {
 	tx := db.GORM.Create(&chart)
+	if isDuplicate(tx.Error) {
+		return ErrChartExists
+	}
 	if tx.Error != nil {
 		zap.L().Error(fmt.Sprintf("添加Chart失败, %v\n", tx.Error))
 		return errors.New(fmt.Sprintf("添加Chart失败, %v\n", tx.Error))
@@ -76,12 +140,17 @@ func (*chart) Add(chart *model.Chart) error {
 // Update 更新
 func (*chart) Update(chart *model.Chart) error {
 	tx := db.GORM.Model(&chart).Updates(&model.Chart{
-		Name:     chart.Name,
-		FileName: chart.FileName,
-		IconUrl:  chart.IconUrl,
-		Version:  chart.Version,
-		Describe: chart.Describe,
+		Name:       chart.Name,
+		FileName:   chart.FileName,
+		IconUrl:    chart.IconUrl,
+		Version:    chart.Version,
+		AppVersion: chart.AppVersion,
+		Describe:   chart.Describe,
+		Digest:     chart.Digest,
 	})
+	if isDuplicate(tx.Error) {
+		return ErrChartExists
+	}
 	if tx.Error != nil {
 		zap.L().Error(fmt.Sprintf("更新Chart失败, %v\n", tx.Error))
 		return errors.New(fmt.Sprintf("更新Chart失败, %v\n", tx.Error))
@@ -89,11 +158,11 @@ func (*chart) Update(chart *model.Chart) error {
 	return nil
 }
 
-// Delete 删除
+// Delete 删除，直接删除记录，否则 Name + Version 唯一索引会阻止重新上传该版本
 func (*chart) Delete(id uint) error {
 	data := &model.Chart{}
 	data.ID = uint(id)
-	tx := db.GORM.Delete(&data)
+	tx := db.GORM.Unscoped().Delete(&data)
 	if tx.Error != nil {
 		zap.L().Error("删除Chart失败, " + tx.Error.Error())
 		return errors.New("删除Chart失败, " + tx.Error.Error())
@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var Cluster cluster
+
+type cluster struct{}
+
+// GetAll 查询所有集群
+func (*cluster) GetAll() ([]*model.Cluster, error) {
+	data := make([]*model.Cluster, 0)
+	tx := db.GORM.Order("name").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取Cluster列表失败," + tx.Error.Error())
+		return nil, errors.New("获取Cluster列表失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Get 根据ID查询集群
+func (*cluster) Get(id uint) (*model.Cluster, bool, error) {
+	data := new(model.Cluster)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据ID查询Cluster失败," + tx.Error.Error())
+		return nil, false, errors.New("根据ID查询Cluster失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Has 根据名称查询集群，用于代码层去重
+func (*cluster) Has(name string) (*model.Cluster, bool, error) {
+	data := new(model.Cluster)
+	tx := db.GORM.Where("name = ?", name).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据名称查询Cluster失败," + tx.Error.Error())
+		return nil, false, errors.New("根据名称查询Cluster失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*cluster) Add(c *model.Cluster) error {
+	tx := db.GORM.Create(&c)
+	if tx.Error != nil {
+		zap.L().Error("新增Cluster信息失败," + tx.Error.Error())
+		return errors.New("新增Cluster信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Update 更新，凭证等零值字段不更新，insecure 单独更新
+func (*cluster) Update(c *model.Cluster) error {
+	tx := db.GORM.Model(&model.Cluster{}).Where("id = ?", c.ID).Updates(&c)
+	if tx.Error != nil {
+		zap.L().Error("更新Cluster信息失败," + tx.Error.Error())
+		return errors.New("更新Cluster信息失败," + tx.Error.Error())
+	}
+
+	tx = db.GORM.Model(&model.Cluster{}).Where("id = ?", c.ID).Update("insecure", c.Insecure)
+	if tx.Error != nil {
+		zap.L().Error("更新Cluster信息失败," + tx.Error.Error())
+		return errors.New("更新Cluster信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除集群及其名称空间权限，避免同名的新集群继承原有权限
+func (*cluster) Delete(id uint, name string) error {
+	tx := db.GORM.Begin()
+	if err := tx.Where("cluster = ?", name).Delete(&model.RoleScope{}).Error; err != nil {
+		tx.Rollback()
+		zap.L().Error("删除Cluster权限失败," + err.Error())
+		return errors.New("删除Cluster权限失败," + err.Error())
+	}
+	data := new(model.Cluster)
+	data.ID = id
+	if err := tx.Delete(&data).Error; err != nil {
+		tx.Rollback()
+		zap.L().Error("删除Cluster信息失败," + err.Error())
+		return errors.New("删除Cluster信息失败," + err.Error())
+	}
+	if err := tx.Commit().Error; err != nil {
+		zap.L().Error("删除Cluster信息失败," + err.Error())
+		return errors.New("删除Cluster信息失败," + err.Error())
+	}
+
+	return nil
+}
@@ -136,30 +136,78 @@ func (*deploy) Delete(deployId uint) error {
 	return nil
 }
 
-// GetLog 查询日志
-func (*deploy) GetLog(deployId uint) (*model.DeployLog, bool, error) {
-	data := new(model.DeployLog)
-	tx := db.GORM.Model(&model.Deploy{}).Where("deploy_id = ?", deployId).First(&data)
-	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
-		return nil, false, nil
+// DeployLogQuery 日志查询条件，Since 不为 0 时返回该条之后的日志，否则按页查询
+type DeployLogQuery struct {
+	DeployId uint  `form:"id"`
+	RunId    int64 `form:"run_id"`
+	Since    uint  `form:"since"`
+	Page     int   `form:"page"`
+	Limit    int   `form:"limit"`
+}
+
+type DeployLogs struct {
+	Items []*model.DeployLog `json:"items"`
+	Total int                `json:"total"`
+	// 最后一条日志的 ID，下次增量查询时作为 since
+	Cursor uint `json:"cursor"`
+}
+
+// ListLogs 查询日志，按 ID 正序，同一发布任务的日志由 service 串行写入，ID 顺序即提交顺序
+func (*deploy) ListLogs(q *DeployLogQuery) (*DeployLogs, error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Limit <= 0 {
+		q.Limit = 500
+	}
+
+	var (
+		logList = make([]*model.DeployLog, 0)
+		total   = 0
+	)
+
+	query := db.GORM.Model(&model.DeployLog{}).Where("deploy_id = ?", q.DeployId)
+	if q.RunId != 0 {
+		query = query.Where("run_id = ?", q.RunId)
 	}
+	tx := query.Count(&total)
 	if tx.Error != nil {
 		zap.L().Error("查询DeployLog失败," + tx.Error.Error())
-		return nil, false, errors.New("查询DeployLog失败," + tx.Error.Error())
+		return nil, errors.New("查询DeployLog失败," + tx.Error.Error())
 	}
 
-	return data, true, nil
+	if q.Since != 0 {
+		query = query.Where("id > ?", q.Since)
+	} else {
+		query = query.Offset((q.Page - 1) * q.Limit)
+	}
+	tx = query.Limit(q.Limit).Order("id").Find(&logList)
+	if tx.Error != nil {
+		zap.L().Error("查询DeployLog失败," + tx.Error.Error())
+		return nil, errors.New("查询DeployLog失败," + tx.Error.Error())
+	}
+
+	cursor := q.Since
+	if len(logList) > 0 {
+		cursor = logList[len(logList)-1].ID
+	}
+	return &DeployLogs{
+		Items:  logList,
+		Total:  total,
+		Cursor: cursor,
+	}, nil
 }
 
-// UpdateLog 修改日志，追加
-func (*deploy) UpdateLog(deployLog *model.DeployLog) error {
-	tx := db.GORM.Model(&model.DeployLog{}).Where("deploy_id = ?", deployLog.DeployId).Updates(&deployLog)
+// CountLogs 统计运行某个来源的日志行数
+func (*deploy) CountLogs(runId int64, source string) (int, error) {
+	total := 0
+	tx := db.GORM.Model(&model.DeployLog{}).Where("run_id = ? and source = ?", runId, source).Count(&total)
 	if tx.Error != nil {
-		zap.L().Error("更新DeployLog失败," + tx.Error.Error())
-		return errors.New("更新DeployLog失败," + tx.Error.Error())
+		zap.L().Error("统计DeployLog失败," + tx.Error.Error())
+		return 0, errors.New("统计DeployLog失败," + tx.Error.Error())
 	}
 
-	return nil
+	return total, nil
 }
 
 // AddLog 新增日志
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var DeployRule deployRule
+
+type deployRule struct{}
+
+// List 查询规则，appId 为 0 时查询所有应用
+func (*deployRule) List(appId uint) ([]*model.DeployRule, error) {
+	data := make([]*model.DeployRule, 0)
+	tx := db.GORM.Model(&model.DeployRule{})
+	if appId != 0 {
+		tx = tx.Where("app_id = ?", appId)
+	}
+	if tx = tx.Order("app_id, id").Find(&data); tx.Error != nil {
+		zap.L().Error("获取DeployRule列表失败," + tx.Error.Error())
+		return nil, errors.New("获取DeployRule列表失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Match 查询应用中指定事件的已启用规则
+func (*deployRule) Match(appId uint, event string) ([]*model.DeployRule, error) {
+	data := make([]*model.DeployRule, 0)
+	tx := db.GORM.Where("app_id = ? and event = ? and enabled = ?", appId, event, true).Order("id").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("查询DeployRule失败," + tx.Error.Error())
+		return nil, errors.New("查询DeployRule失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Get 根据ID查询
+func (*deployRule) Get(id uint) (*model.DeployRule, bool, error) {
+	data := new(model.DeployRule)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据ID查询DeployRule失败," + tx.Error.Error())
+		return nil, false, errors.New("根据ID查询DeployRule失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*deployRule) Add(r *model.DeployRule) error {
+	tx := db.GORM.Create(&r)
+	if tx.Error != nil {
+		zap.L().Error("新增DeployRule失败," + tx.Error.Error())
+		return errors.New("新增DeployRule失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Save 更新所有字段，approval、enabled 等零值也会更新
+func (*deployRule) Save(r *model.DeployRule) error {
+	tx := db.GORM.Save(&r)
+	if tx.Error != nil {
+		zap.L().Error("更新DeployRule失败," + tx.Error.Error())
+		return errors.New("更新DeployRule失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除
+func (*deployRule) Delete(id uint) error {
+	tx := db.GORM.Where("id = ?", id).Delete(&model.DeployRule{})
+	if tx.Error != nil {
+		zap.L().Error("删除DeployRule失败," + tx.Error.Error())
+		return errors.New("删除DeployRule失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package dao
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var DeployRun deployRun
+
+type deployRun struct{}
+
+// ErrDeployRunActive 应用在环境中已有进行中的运行，active_key 唯一索引冲突
+var ErrDeployRunActive = errors.New("该应用在该环境已有进行中的发布")
+
+// isDuplicate 是否为唯一索引冲突
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+type DeployRuns struct {
+	Items []*model.DeployRun `json:"items"`
+	Total int                `json:"total"`
+}
+
+// List 查询发布的运行记录
+func (*deployRun) List(deployId uint, page, limit int) (*DeployRuns, error) {
+	startSet := (page - 1) * limit
+
+	var (
+		runList = make([]*model.DeployRun, 0)
+		total   = 0
+	)
+
+	tx := db.GORM.Model(&model.DeployRun{}).
+		Where("deploy_id = ?", deployId).
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&runList)
+	if tx.Error != nil {
+		zap.L().Error("获取DeployRun列表失败," + tx.Error.Error())
+		return nil, errors.New("获取DeployRun列表失败," + tx.Error.Error())
+	}
+
+	return &DeployRuns{
+		Items: runList,
+		Total: total,
+	}, nil
+}
+
+// Get 根据 run ID 查询
+func (*deployRun) Get(id int64) (*model.DeployRun, bool, error) {
+	data := new(model.DeployRun)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if tx.Error != nil {
+		zap.L().Error("查询DeployRun失败," + tx.Error.Error())
+		return nil, false, errors.New("查询DeployRun失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Active 查询应用在环境中进行中的运行
+func (*deployRun) Active(appId uint, en string) (*model.DeployRun, bool, error) {
+	data := new(model.DeployRun)
+	tx := db.GORM.
+		Where("app_id = ? and en = ?", appId, en).
+		Where("state in (?)", []model.DeployState{model.DeployQueued, model.DeployCodeCheck, model.DeployBuilding, model.DeployDeploying}).
+		Order("id desc").
+		First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if tx.Error != nil {
+		zap.L().Error("查询进行中的DeployRun失败," + tx.Error.Error())
+		return nil, false, errors.New("查询进行中的DeployRun失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*deployRun) Add(run *model.DeployRun) error {
+	tx := db.GORM.Create(&run)
+	if isDuplicate(tx.Error) {
+		return ErrDeployRunActive
+	}
+	if tx.Error != nil {
+		zap.L().Error("新增DeployRun失败," + tx.Error.Error())
+		return errors.New("新增DeployRun失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Transit 状态迁移，只有当前状态仍为 from 时才更新，返回是否更新成功
+func (*deployRun) Transit(id int64, from model.DeployState, updates map[string]interface{}) (bool, error) {
+	tx := db.GORM.Model(&model.DeployRun{}).
+		Where("id = ? and state = ?", id, from).
+		Updates(updates)
+	if isDuplicate(tx.Error) {
+		return false, ErrDeployRunActive
+	}
+	if tx.Error != nil {
+		zap.L().Error("更新DeployRun状态失败," + tx.Error.Error())
+		return false, errors.New("更新DeployRun状态失败," + tx.Error.Error())
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+// UpdateBuild 记录 Jenkins 队列项及构建信息，零值字段不更新
+func (*deployRun) UpdateBuild(id int64, queueUrl string, buildNumber int, buildUrl string) error {
+	tx := db.GORM.Model(&model.DeployRun{}).Where("id = ?", id).Updates(&model.DeployRun{
+		QueueUrl:    queueUrl,
+		BuildNumber: buildNumber,
+		BuildUrl:    buildUrl,
+	})
+	if tx.Error != nil {
+		zap.L().Error("更新DeployRun构建信息失败," + tx.Error.Error())
+		return errors.New("更新DeployRun构建信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Update 更新运行记录，零值字段不更新
+func (*deployRun) Update(id int64, run *model.DeployRun) error {
+	tx := db.GORM.Model(&model.DeployRun{}).Where("id = ?", id).Updates(run)
+	if tx.Error != nil {
+		zap.L().Error("更新DeployRun失败," + tx.Error.Error())
+		return errors.New("更新DeployRun失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Pending 查询等待审批的运行
+func (*deployRun) Pending() ([]*model.DeployRun, error) {
+	data := make([]*model.DeployRun, 0)
+	tx := db.GORM.Where("state = ?", model.DeployPending).Order("id desc").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取待审批DeployRun失败," + tx.Error.Error())
+		return nil, errors.New("获取待审批DeployRun失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Unfinished 查询未结束且有 Jenkins 队列项或构建地址的运行，用于重启后恢复跟踪
+func (*deployRun) Unfinished() ([]*model.DeployRun, error) {
+	data := make([]*model.DeployRun, 0)
+	tx := db.GORM.
+		Where("state in (?)", []model.DeployState{model.DeployQueued, model.DeployCodeCheck, model.DeployBuilding, model.DeployDeploying}).
+		Where("queue_url <> '' or build_url <> ''").
+		Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取未结束的DeployRun失败," + tx.Error.Error())
+		return nil, errors.New("获取未结束的DeployRun失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
@@ -75,6 +75,22 @@ func (*env) List(envName string, page, limit int) (*Envs, error) {
 //	return data, true, nil
 //}
 
+// Get 根据ID查询
+func (*env) Get(id uint) (*model.Env, bool, error) {
+	data := new(model.Env)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("查询Env信息失败," + tx.Error.Error())
+		return nil, false, errors.New("查询Env信息失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
 // Has 根据环境名查询，用于代码层去重，查询账号信息
 func (*env) Has(envName string) (*model.Env, bool, error) {
 	data := new(model.Env)
@@ -113,6 +129,67 @@ func (*env) Update(e *model.Env) error {
 	return nil
 }
 
+// GetChain 查询晋级链中的环境，按顺序排列
+func (*env) GetChain() ([]*model.Env, error) {
+	data := make([]*model.Env, 0)
+	tx := db.GORM.Where("sort > 0").Order("sort, id").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取Env晋级链失败," + tx.Error.Error())
+		return nil, errors.New("获取Env晋级链失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// UpdatePolicy 更新晋级顺序及是否需要审批，零值也会更新
+func (*env) UpdatePolicy(e *model.Env) error {
+	tx := db.GORM.Model(&model.Env{}).Where("id = ?", e.ID).Updates(map[string]interface{}{
+		"sort":     e.Sort,
+		"approval": e.Approval,
+	})
+	if tx.Error != nil {
+		zap.L().Error("更新Env晋级策略失败," + tx.Error.Error())
+		return errors.New("更新Env晋级策略失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// GetApprovers 查询环境的审批角色
+func (*env) GetApprovers(envID uint) ([]*model.EnvApprover, error) {
+	data := make([]*model.EnvApprover, 0)
+	tx := db.GORM.Where("env_id = ?", envID).Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取Env审批角色失败," + tx.Error.Error())
+		return nil, errors.New("获取Env审批角色失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// SetApprovers 替换环境的审批角色
+func (*env) SetApprovers(envID uint, roleIDs []uint) error {
+	tx := db.GORM.Begin()
+	if err := tx.Where("env_id = ?", envID).Delete(&model.EnvApprover{}).Error; err != nil {
+		tx.Rollback()
+		zap.L().Error("更新Env审批角色失败," + err.Error())
+		return errors.New("更新Env审批角色失败," + err.Error())
+	}
+	for _, roleID := range roleIDs {
+		if err := tx.Create(&model.EnvApprover{EnvID: envID, RoleID: roleID}).Error; err != nil {
+			tx.Rollback()
+			zap.L().Error("更新Env审批角色失败," + err.Error())
+			return errors.New("更新Env审批角色失败," + err.Error())
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		zap.L().Error("更新Env审批角色失败," + err.Error())
+		return errors.New("更新Env审批角色失败," + err.Error())
+	}
+
+	return nil
+}
+
 // Delete 删除
 func (*env) Delete(id uint) error {
 	data := new(model.Env)
@@ -20,7 +20,8 @@ type Events struct {
 }
 
 // GetList 获取列表
-func (*event) GetList(name, cluster string, page, limit int) (*Events, error) {
+// namespaces 为 nil 时不限制名称空间
+func (*event) GetList(name, cluster string, namespaces []string, page, limit int) (*Events, error) {
 	//定义分页数据的起始位置
 	startSet := (page - 1) * limit
 	//定义数据库查询的返回内容
@@ -29,10 +30,14 @@ func (*event) GetList(name, cluster string, page, limit int) (*Events, error) {
 		total     = 0
 	)
 
+	query := db.GORM.Model(&model.Event{}).
+		Where("name like ? and cluster = ?", "%"+name+"%", cluster)
+	if namespaces != nil {
+		query = query.Where("namespace in (?)", namespaces)
+	}
+
 	//数据库查询，先查total
-	tx := db.GORM.Model(&model.Event{}).
-		Where("name like ? and cluster = ?", "%"+name+"%", cluster).
-		Count(&total)
+	tx := query.Count(&total)
 
 	if tx.Error != nil {
 		zap.L().Error("获取Event列表失败," + tx.Error.Error())
@@ -40,9 +45,7 @@ func (*event) GetList(name, cluster string, page, limit int) (*Events, error) {
 	}
 
 	//数据库查询
-	tx = db.GORM.Model(&model.Event{}).
-		Where("name like ? and cluster = ?", "%"+name+"%", cluster).
-		Limit(limit).
+	tx = query.Limit(limit).
 		Offset(startSet).
 		Order("id desc").
 		Find(&eventList)
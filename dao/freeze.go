@@ -0,0 +1,130 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var Freeze freeze
+
+type freeze struct{}
+
+// List 查询冻结窗口，en 为空时查询所有
+func (*freeze) List(en string) ([]*model.FreezeWindow, error) {
+	data := make([]*model.FreezeWindow, 0)
+	tx := db.GORM.Model(&model.FreezeWindow{})
+	if en != "" {
+		tx = tx.Where("en = ?", en)
+	}
+	if tx = tx.Order("id").Find(&data); tx.Error != nil {
+		zap.L().Error("获取FreezeWindow列表失败," + tx.Error.Error())
+		return nil, errors.New("获取FreezeWindow列表失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// GetEnabled 查询作用于环境的已启用窗口，包含 * 的窗口
+func (*freeze) GetEnabled(en string) ([]*model.FreezeWindow, error) {
+	data := make([]*model.FreezeWindow, 0)
+	tx := db.GORM.Where("en in (?) and enabled = ?", []string{en, "*"}, true).Order("id").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("查询FreezeWindow失败," + tx.Error.Error())
+		return nil, errors.New("查询FreezeWindow失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Get 根据ID查询
+func (*freeze) Get(id uint) (*model.FreezeWindow, bool, error) {
+	data := new(model.FreezeWindow)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据ID查询FreezeWindow失败," + tx.Error.Error())
+		return nil, false, errors.New("根据ID查询FreezeWindow失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*freeze) Add(w *model.FreezeWindow) error {
+	tx := db.GORM.Create(&w)
+	if tx.Error != nil {
+		zap.L().Error("新增FreezeWindow失败," + tx.Error.Error())
+		return errors.New("新增FreezeWindow失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Save 更新所有字段，enabled 等零值也会更新
+func (*freeze) Save(w *model.FreezeWindow) error {
+	tx := db.GORM.Save(&w)
+	if tx.Error != nil {
+		zap.L().Error("更新FreezeWindow失败," + tx.Error.Error())
+		return errors.New("更新FreezeWindow失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除窗口及紧急发布角色
+func (*freeze) Delete(id uint) error {
+	tx := db.GORM.Where("id = ?", id).Delete(&model.FreezeWindow{})
+	if tx.Error != nil {
+		zap.L().Error("删除FreezeWindow失败," + tx.Error.Error())
+		return errors.New("删除FreezeWindow失败," + tx.Error.Error())
+	}
+
+	tx = db.GORM.Where("window_id = ?", id).Delete(&model.FreezeOverride{})
+	if tx.Error != nil {
+		zap.L().Error("删除FreezeOverride失败," + tx.Error.Error())
+		return errors.New("删除FreezeOverride失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// GetOverrides 查询窗口允许紧急发布的角色
+func (*freeze) GetOverrides(windowID uint) ([]*model.FreezeOverride, error) {
+	data := make([]*model.FreezeOverride, 0)
+	tx := db.GORM.Where("window_id = ?", windowID).Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取FreezeOverride失败," + tx.Error.Error())
+		return nil, errors.New("获取FreezeOverride失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// SetOverrides 替换窗口允许紧急发布的角色
+func (*freeze) SetOverrides(windowID uint, roleIDs []uint) error {
+	tx := db.GORM.Begin()
+	if err := tx.Where("window_id = ?", windowID).Delete(&model.FreezeOverride{}).Error; err != nil {
+		tx.Rollback()
+		zap.L().Error("更新FreezeOverride失败," + err.Error())
+		return errors.New("更新FreezeOverride失败," + err.Error())
+	}
+	for _, roleID := range roleIDs {
+		if err := tx.Create(&model.FreezeOverride{WindowID: windowID, RoleID: roleID}).Error; err != nil {
+			tx.Rollback()
+			zap.L().Error("更新FreezeOverride失败," + err.Error())
+			return errors.New("更新FreezeOverride失败," + err.Error())
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		zap.L().Error("更新FreezeOverride失败," + err.Error())
+		return errors.New("更新FreezeOverride失败," + err.Error())
+	}
+
+	return nil
+}
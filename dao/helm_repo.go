@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+	"time"
+)
+
+var HelmRepo helmRepo
+
+type helmRepo struct{}
+
+// GetAll 查询所有仓库
+func (*helmRepo) GetAll() ([]*model.HelmRepo, error) {
+	data := make([]*model.HelmRepo, 0)
+	tx := db.GORM.Order("name").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取HelmRepo列表失败," + tx.Error.Error())
+		return nil, errors.New("获取HelmRepo列表失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Get 根据ID查询仓库
+func (*helmRepo) Get(id uint) (*model.HelmRepo, bool, error) {
+	data := new(model.HelmRepo)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据ID查询HelmRepo失败," + tx.Error.Error())
+		return nil, false, errors.New("根据ID查询HelmRepo失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Has 根据名称查询仓库，用于代码层去重
+func (*helmRepo) Has(name string) (*model.HelmRepo, bool, error) {
+	data := new(model.HelmRepo)
+	tx := db.GORM.Where("name = ?", name).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据名称查询HelmRepo失败," + tx.Error.Error())
+		return nil, false, errors.New("根据名称查询HelmRepo失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*helmRepo) Add(r *model.HelmRepo) error {
+	tx := db.GORM.Create(&r)
+	if tx.Error != nil {
+		zap.L().Error("新增HelmRepo信息失败," + tx.Error.Error())
+		return errors.New("新增HelmRepo信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Update 更新，凭证等零值字段不更新，insecure 单独更新
+func (*helmRepo) Update(r *model.HelmRepo) error {
+	tx := db.GORM.Model(&model.HelmRepo{}).Where("id = ?", r.ID).Updates(&r)
+	if tx.Error != nil {
+		zap.L().Error("更新HelmRepo信息失败," + tx.Error.Error())
+		return errors.New("更新HelmRepo信息失败," + tx.Error.Error())
+	}
+
+	tx = db.GORM.Model(&model.HelmRepo{}).Where("id = ?", r.ID).Update("insecure", r.Insecure)
+	if tx.Error != nil {
+		zap.L().Error("更新HelmRepo信息失败," + tx.Error.Error())
+		return errors.New("更新HelmRepo信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// UpdateRefresh 记录刷新结果，refreshError 为空表示刷新成功
+func (*helmRepo) UpdateRefresh(id uint, refreshedAt time.Time, refreshError string) error {
+	tx := db.GORM.Model(&model.HelmRepo{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"refreshed_at":  refreshedAt,
+		"refresh_error": refreshError,
+	})
+	if tx.Error != nil {
+		zap.L().Error("更新HelmRepo刷新状态失败," + tx.Error.Error())
+		return errors.New("更新HelmRepo刷新状态失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除
+func (*helmRepo) Delete(id uint) error {
+	data := new(model.HelmRepo)
+	data.ID = id
+	tx := db.GORM.Delete(&data)
+	if tx.Error != nil {
+		zap.L().Error("删除HelmRepo信息失败," + tx.Error.Error())
+		return errors.New("删除HelmRepo信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var Permission permission
+
+type permission struct{}
+
+type Permissions struct {
+	Items []*model.Permission `json:"items"`
+	Total int                 `json:"total"`
+}
+
+// List 列表
+// name用于模糊查询名称和路径，过滤
+// page，limit用于分页
+func (*permission) List(name string, page, limit int) (*Permissions, error) {
+	//计算分页
+	startSet := (page - 1) * limit
+
+	//定义返回值的内容
+	var (
+		permissionList = make([]*model.Permission, 0)
+		total          = 0
+	)
+
+	query := db.GORM.Model(&model.Permission{})
+	if name != "" {
+		query = query.Where("name like ? or path like ?", "%"+name+"%", "%"+name+"%")
+	}
+
+	tx := query.Count(&total)
+	if tx.Error != nil {
+		zap.L().Error("获取Permission列表失败," + tx.Error.Error())
+		return nil, errors.New("获取Permission列表失败," + tx.Error.Error())
+	}
+
+	//分页数据
+	tx = query.Limit(limit).
+		Offset(startSet).
+		Order("path, method").
+		Find(&permissionList)
+	if tx.Error != nil {
+		zap.L().Error("获取Permission列表失败," + tx.Error.Error())
+		return nil, errors.New("获取Permission列表失败," + tx.Error.Error())
+	}
+
+	return &Permissions{
+		Items: permissionList,
+		Total: total,
+	}, nil
+}
+
+// GetAll 查询所有接口权限
+func (*permission) GetAll() ([]*model.Permission, error) {
+	data := make([]*model.Permission, 0)
+	tx := db.GORM.Order("path, method").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("查询所有Permission失败," + tx.Error.Error())
+		return nil, errors.New("查询所有Permission失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Has 根据请求方法和路径查询，用于代码层去重
+func (*permission) Has(method, path string) (*model.Permission, bool, error) {
+	data := new(model.Permission)
+	tx := db.GORM.Where("method = ? and path = ?", method, path).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据路径查询Permission失败," + tx.Error.Error())
+		return nil, false, errors.New("根据路径查询Permission失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Allowed 查询角色是否绑定了该接口权限
+func (*permission) Allowed(roleID uint, method, path string) (bool, error) {
+	total := 0
+	tx := db.GORM.Model(&model.RolePermission{}).
+		Joins("join permission on role_permission.permission_id = permission.id").
+		Where("role_permission.role_id = ? and permission.method = ? and permission.path = ?", roleID, method, path).
+		Count(&total)
+	if tx.Error != nil {
+		zap.L().Error("查询RolePermission失败," + tx.Error.Error())
+		return false, errors.New("查询RolePermission失败," + tx.Error.Error())
+	}
+
+	return total > 0, nil
+}
+
+// Add 新增
+func (*permission) Add(p *model.Permission) error {
+	tx := db.GORM.Create(&p)
+	if tx.Error != nil {
+		zap.L().Error("新增Permission信息失败," + tx.Error.Error())
+		return errors.New("新增Permission信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Update 更新
+func (*permission) Update(p *model.Permission) error {
+	tx := db.GORM.Model(&model.Permission{}).Where("id = ?", p.ID).Updates(&p)
+	if tx.Error != nil {
+		zap.L().Error("更新Permission信息失败," + tx.Error.Error())
+		return errors.New("更新Permission信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除，同时删除角色上的绑定关系
+func (*permission) Delete(id uint) error {
+	tx := db.GORM.Where("permission_id = ?", id).Delete(&model.RolePermission{})
+	if tx.Error != nil {
+		zap.L().Error("删除RolePermission信息失败," + tx.Error.Error())
+		return errors.New("删除RolePermission信息失败," + tx.Error.Error())
+	}
+
+	data := new(model.Permission)
+	data.ID = id
+	tx = db.GORM.Delete(&data)
+	if tx.Error != nil {
+		zap.L().Error("删除Permission信息失败," + tx.Error.Error())
+		return errors.New("删除Permission信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// GetRole 根据 roleID 查询角色绑定的接口权限
+func (*permission) GetRole(roleID uint) ([]*model.RolePermission, error) {
+	data := make([]*model.RolePermission, 0)
+	tx := db.GORM.Where("role_id = ?", roleID).Order("permission_id").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("根据RoleID查询RolePermission失败," + tx.Error.Error())
+		return nil, errors.New("根据RoleID查询RolePermission失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// AddRole 角色绑定接口权限
+func (*permission) AddRole(r *model.RolePermission) error {
+	tx := db.GORM.Create(&r)
+	if tx.Error != nil {
+		zap.L().Error("新增RolePermission信息失败," + tx.Error.Error())
+		return errors.New("新增RolePermission信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// DeleteRole 角色解绑接口权限
+func (*permission) DeleteRole(roleID, permissionID uint) error {
+	tx := db.GORM.Where("role_id = ? and permission_id = ?", roleID, permissionID).Delete(&model.RolePermission{})
+	if tx.Error != nil {
+		zap.L().Error("删除RolePermission信息失败," + tx.Error.Error())
+		return errors.New("删除RolePermission信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var Scope scope
+
+type scope struct{}
+
+type Scopes struct {
+	Items []*model.RoleScope `json:"items"`
+	Total int                `json:"total"`
+}
+
+// List 列表
+// roleID，cluster用于过滤
+// page，limit用于分页
+func (*scope) List(roleID uint, cluster string, page, limit int) (*Scopes, error) {
+	//计算分页
+	startSet := (page - 1) * limit
+
+	//定义返回值的内容
+	var (
+		scopeList = make([]*model.RoleScope, 0)
+		total     = 0
+	)
+
+	query := db.GORM.Model(&model.RoleScope{})
+	if roleID != 0 {
+		query = query.Where("role_id = ?", roleID)
+	}
+	if cluster != "" {
+		query = query.Where("cluster = ?", cluster)
+	}
+
+	tx := query.Count(&total)
+	if tx.Error != nil {
+		zap.L().Error("获取RoleScope列表失败," + tx.Error.Error())
+		return nil, errors.New("获取RoleScope列表失败," + tx.Error.Error())
+	}
+
+	//分页数据
+	tx = query.Limit(limit).
+		Offset(startSet).
+		Order("role_id, cluster, namespace").
+		Find(&scopeList)
+	if tx.Error != nil {
+		zap.L().Error("获取RoleScope列表失败," + tx.Error.Error())
+		return nil, errors.New("获取RoleScope列表失败," + tx.Error.Error())
+	}
+
+	return &Scopes{
+		Items: scopeList,
+		Total: total,
+	}, nil
+}
+
+// GetCluster 查询角色在集群中的名称空间权限
+func (*scope) GetCluster(roleID uint, cluster string) ([]*model.RoleScope, error) {
+	data := make([]*model.RoleScope, 0)
+	tx := db.GORM.Where("role_id = ? and cluster = ?", roleID, cluster).Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("根据RoleID查询RoleScope失败," + tx.Error.Error())
+		return nil, errors.New("根据RoleID查询RoleScope失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Has 查询角色在集群名称空间中的权限，用于代码层去重
+func (*scope) Has(roleID uint, cluster, namespace string) (*model.RoleScope, bool, error) {
+	data := new(model.RoleScope)
+	tx := db.GORM.Where("role_id = ? and cluster = ? and namespace = ?", roleID, cluster, namespace).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("查询RoleScope失败," + tx.Error.Error())
+		return nil, false, errors.New("查询RoleScope失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*scope) Add(s *model.RoleScope) error {
+	tx := db.GORM.Create(&s)
+	if tx.Error != nil {
+		zap.L().Error("新增RoleScope信息失败," + tx.Error.Error())
+		return errors.New("新增RoleScope信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Update 更新
+func (*scope) Update(s *model.RoleScope) error {
+	tx := db.GORM.Model(&model.RoleScope{}).Where("id = ?", s.ID).Updates(&s)
+	if tx.Error != nil {
+		zap.L().Error("更新RoleScope信息失败," + tx.Error.Error())
+		return errors.New("更新RoleScope信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Delete 删除
+func (*scope) Delete(id uint) error {
+	data := new(model.RoleScope)
+	data.ID = id
+	tx := db.GORM.Delete(&data)
+	if tx.Error != nil {
+		zap.L().Error("删除RoleScope信息失败," + tx.Error.Error())
+		return errors.New("删除RoleScope信息失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+	"time"
+)
+
+var Terminal terminal
+
+type terminal struct{}
+
+type TerminalRecords struct {
+	Items []*model.TerminalRecord `json:"items"`
+	Total int                     `json:"total"`
+}
+
+// TerminalQuery 终端会话的过滤条件，零值不过滤
+type TerminalQuery struct {
+	UserName  string    `form:"user_name"`
+	Cluster   string    `form:"cluster"`
+	Namespace string    `form:"namespace"`
+	Pod       string    `form:"pod"`
+	StartTime time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
+	EndTime   time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
+	Page      int       `form:"page"`
+	Limit     int       `form:"limit"`
+}
+
+// List 列表
+func (*terminal) List(q *TerminalQuery) (*TerminalRecords, error) {
+	//计算分页
+	startSet := (q.Page - 1) * q.Limit
+
+	//定义返回值的内容
+	var (
+		recordList = make([]*model.TerminalRecord, 0)
+		total      = 0
+	)
+
+	query := db.GORM.Model(&model.TerminalRecord{})
+	if q.UserName != "" {
+		query = query.Where("user_name = ?", q.UserName)
+	}
+	if q.Cluster != "" {
+		query = query.Where("cluster = ?", q.Cluster)
+	}
+	if q.Namespace != "" {
+		query = query.Where("namespace = ?", q.Namespace)
+	}
+	if q.Pod != "" {
+		query = query.Where("pod like ?", "%"+q.Pod+"%")
+	}
+	if !q.StartTime.IsZero() {
+		query = query.Where("started_at >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		query = query.Where("started_at <= ?", q.EndTime)
+	}
+
+	tx := query.Count(&total)
+	if tx.Error != nil {
+		zap.L().Error("获取TerminalRecord列表失败," + tx.Error.Error())
+		return nil, errors.New("获取TerminalRecord列表失败," + tx.Error.Error())
+	}
+
+	//分页数据
+	tx = query.Limit(q.Limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&recordList)
+	if tx.Error != nil {
+		zap.L().Error("获取TerminalRecord列表失败," + tx.Error.Error())
+		return nil, errors.New("获取TerminalRecord列表失败," + tx.Error.Error())
+	}
+
+	return &TerminalRecords{
+		Items: recordList,
+		Total: total,
+	}, nil
+}
+
+// Get 根据ID查询
+func (*terminal) Get(id int64) (*model.TerminalRecord, bool, error) {
+	data := new(model.TerminalRecord)
+	tx := db.GORM.Where("id = ?", id).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据ID查询TerminalRecord失败," + tx.Error.Error())
+		return nil, false, errors.New("根据ID查询TerminalRecord失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*terminal) Add(r *model.TerminalRecord) error {
+	tx := db.GORM.Create(&r)
+	if tx.Error != nil {
+		zap.L().Error("新增TerminalRecord失败," + tx.Error.Error())
+		return errors.New("新增TerminalRecord失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Finish 会话结束，记录实际执行的命令、结束时间、时长及录像大小
+func (*terminal) Finish(id int64, command string, endedAt time.Time, duration, size int64) error {
+	tx := db.GORM.Model(&model.TerminalRecord{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"command":  command,
+		"ended_at": endedAt,
+		"duration": duration,
+		"size":     size,
+	})
+	if tx.Error != nil {
+		zap.L().Error("更新TerminalRecord失败," + tx.Error.Error())
+		return errors.New("更新TerminalRecord失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+	"time"
+)
+
+var Token token
+
+type token struct{}
+
+// Revoke 注销token
+func (*token) Revoke(t *model.RevokedToken) error {
+	tx := db.GORM.Create(&t)
+	if tx.Error != nil {
+		zap.L().Error("注销Token失败," + tx.Error.Error())
+		return errors.New("注销Token失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Revoked 查询token是否已注销
+func (*token) Revoked(tokenID string) (bool, error) {
+	data := new(model.RevokedToken)
+	tx := db.GORM.Where("token_id = ?", tokenID).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("查询Token注销记录失败," + tx.Error.Error())
+		return false, errors.New("查询Token注销记录失败," + tx.Error.Error())
+	}
+
+	return true, nil
+}
+
+// Clean 清理已过期的注销记录，过期的token本身已无法通过校验
+func (*token) Clean() error {
+	tx := db.GORM.Where("expires_at < ?", time.Now()).Delete(&model.RevokedToken{})
+	if tx.Error != nil {
+		zap.L().Error("清理Token注销记录失败," + tx.Error.Error())
+		return errors.New("清理Token注销记录失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"errors"
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+	"kubea/db"
+	"kubea/model"
+)
+
+var Webhook webhook
+
+type webhook struct{}
+
+// GetAll 查询所有集成
+func (*webhook) GetAll() ([]*model.Webhook, error) {
+	data := make([]*model.Webhook, 0)
+	tx := db.GORM.Order("name").Find(&data)
+	if tx.Error != nil {
+		zap.L().Error("获取Webhook列表失败," + tx.Error.Error())
+		return nil, errors.New("获取Webhook列表失败," + tx.Error.Error())
+	}
+
+	return data, nil
+}
+
+// Has 根据名称查询
+func (*webhook) Has(name string) (*model.Webhook, bool, error) {
+	data := new(model.Webhook)
+	tx := db.GORM.Where("name = ?", name).First(&data)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+
+	if tx.Error != nil {
+		zap.L().Error("根据名称查询Webhook失败," + tx.Error.Error())
+		return nil, false, errors.New("根据名称查询Webhook失败," + tx.Error.Error())
+	}
+
+	return data, true, nil
+}
+
+// Add 新增
+func (*webhook) Add(w *model.Webhook) error {
+	tx := db.GORM.Create(&w)
+	if tx.Error != nil {
+		zap.L().Error("新增Webhook失败," + tx.Error.Error())
+		return errors.New("新增Webhook失败," + tx.Error.Error())
+	}
+
+	return nil
+}
+
+// Save 更新所有字段，enabled 等零值也会更新
+func (*webhook) Save(w *model.Webhook) error {
+	tx := db.GORM.Save(&w)
+	if tx.Error != nil {
+		zap.L().Error("更新Webhook失败," + tx.Error.Error())
+		return errors.New("更新Webhook失败," + tx.Error.Error())
+	}
+
+	return nil
+}
@@ -45,14 +45,20 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 	GORM.DB().SetConnMaxLifetime(time.Duration(cfg.MaxLifeTime) * time.Second)
 
 	//isInit = true
+	migrateRolePermission()
 	GORM.AutoMigrate(
 		model.App{},
 		model.Chart{},
 		model.Deploy{},
 		model.DeployLog{},
+		model.DeployRun{},
+		model.DeployRule{},
+		model.FreezeWindow{},
+		model.FreezeOverride{},
 		model.Event{},
 		model.User{},
 		model.Env{},
+		model.EnvApprover{},
 		model.Password{},
 		model.Service{},
 		model.Menu{},
@@ -60,7 +66,20 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 		model.SubSubMenu{},
 		model.Role{},
 		model.RoleMenuRelation{},
+		model.RevokedToken{},
+		model.Permission{},
+		model.RolePermission{},
+		model.RoleScope{},
+		model.Cluster{},
+		model.HelmRepo{},
+		model.Webhook{},
+		model.AuditLog{},
+		model.TerminalRecord{},
 	)
+	migrateDeployState()
+	if err = migrateDeployRunActiveKey(); err != nil {
+		return err
+	}
 	zap.L().Info("数据库连接成功")
 	return
 }
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"go.uber.org/zap"
+)
+
+// migrateDeployState 旧版本的发布状态保存在 status 等 int 字段中，迁移到 state
+// status: 1 开始发布 2 编译中 3 部署完成 4 失败，code_check 为 2 时代码检查失败
+// 只处理 state 为空的记录，可重复执行，旧字段保留不删除
+func migrateDeployState() {
+	if !GORM.Dialect().HasColumn("deploy", "status") {
+		return
+	}
+
+	tx := GORM.Exec(`UPDATE deploy SET state = CASE
+		WHEN code_check = 2 OR status = 4 THEN 'failed'
+		WHEN status = 3 THEN 'succeeded'
+		WHEN status = 2 THEN 'building'
+		ELSE 'queued'
+	END
+	WHERE (state = '' OR state IS NULL) AND (status IN (1, 2, 3, 4) OR code_check = 2)`)
+	if tx.Error != nil {
+		zap.L().Error("迁移Deploy状态失败," + tx.Error.Error())
+		return
+	}
+	if tx.RowsAffected > 0 {
+		zap.L().Info("迁移Deploy状态", zap.Int64("rows", tx.RowsAffected))
+	}
+}
+
+// migrateRolePermission 新增 (role_id, permission_id) 唯一索引前删除重复的绑定，保留最早的一条
+// 需在 AutoMigrate 之前执行，否则唯一索引创建失败
+func migrateRolePermission() {
+	if !GORM.HasTable("role_permission") {
+		return
+	}
+
+	tx := GORM.Exec(`DELETE a FROM role_permission a JOIN role_permission b
+	ON a.role_id = b.role_id AND a.permission_id = b.permission_id AND a.id > b.id`)
+	if tx.Error != nil {
+		zap.L().Error("删除重复的RolePermission失败," + tx.Error.Error())
+		return
+	}
+	if tx.RowsAffected > 0 {
+		zap.L().Info("删除重复的RolePermission", zap.Int64("rows", tx.RowsAffected))
+	}
+}
+
+// migrateDeployRunActiveKey 新增 active_key 前已在进行中的运行补写 active_key，使其同样受唯一索引约束
+// 同一应用环境有多个进行中的运行时只保留最新的，其余标记为失败，否则补写会违反唯一索引
+// 失败时返回错误，停止启动，避免进行中的运行不受锁约束
+func migrateDeployRunActiveKey() error {
+	tx := GORM.Exec(`UPDATE deploy_run r JOIN (
+		SELECT app_id, en, MAX(id) AS id FROM deploy_run
+		WHERE state IN ('queued', 'code-check', 'building', 'deploying')
+		GROUP BY app_id, en
+	) latest ON r.app_id = latest.app_id AND r.en = latest.en
+	SET r.state = 'failed', r.active_key = NULL, r.finished_at = NOW(),
+		r.message = '同一应用环境存在多个进行中的运行，升级时保留最新的运行'
+	WHERE r.state IN ('queued', 'code-check', 'building', 'deploying') AND r.id < latest.id`)
+	if tx.Error != nil {
+		zap.L().Error("迁移DeployRun active_key失败," + tx.Error.Error())
+		return errors.New("迁移DeployRun active_key失败," + tx.Error.Error())
+	}
+	if tx.RowsAffected > 0 {
+		zap.L().Warn("同一应用环境存在多个进行中的运行，已将较早的运行标记为失败", zap.Int64("rows", tx.RowsAffected))
+	}
+
+	tx = GORM.Exec(`UPDATE deploy_run SET active_key = CONCAT(app_id, '/', en)
+	WHERE active_key IS NULL AND state IN ('queued', 'code-check', 'building', 'deploying')`)
+	if tx.Error != nil {
+		zap.L().Error("迁移DeployRun active_key失败," + tx.Error.Error())
+		return errors.New("迁移DeployRun active_key失败," + tx.Error.Error())
+	}
+	return nil
+}
@@ -72,6 +72,12 @@ func GinLogger() gin.HandlerFunc {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
+		// ?token= 不写入日志
+		if c.Query("token") != "" {
+			values := c.Request.URL.Query()
+			values.Set("token", "***")
+			query = values.Encode()
+		}
 		c.Next()
 
 		cost := time.Since(start)
@@ -7,6 +7,7 @@ import (
 	"kubea/middle/snowflake"
 	"kubea/routers"
 	"kubea/service"
+	"kubea/utils"
 	"net/http"
 	"os"
 	"os/signal"
@@ -35,6 +36,12 @@ func main() {
 	defer zap.L().Sync()
 	zap.L().Debug("logger init success...")
 
+	// 加载JWT密钥
+	if err := utils.JWTToken.Init(); err != nil {
+		zap.L().Error("init jwt failed", zap.Error(err))
+		return
+	}
+
 	// 3. 初始化MySQL连接
 	if err := db.Init(settings.Conf.MySQLConfig); err != nil {
 		zap.L().Error("init mysql failed, err:%v\n", zap.Error(err))
@@ -46,8 +53,15 @@ func main() {
 		}
 	}()
 
-	// 4. 初始化k8s client
+	// 初始化 webhook 密钥
+	if err := service.Webhook.Init(); err != nil {
+		zap.L().Error("init webhook failed", zap.Error(err))
+		return
+	}
+
+	// 4. 初始化k8s client，并启动各集群的 event 监听
 	service.K8s.Init(settings.Conf.KubeConfigs)
+	go service.K8s.ProbeTask(settings.Conf.ClusterProbeInterval)
 
 	// 5. 注册雪花算法 ID 生成器
 	if err := snowflake.Init(settings.Conf.StartTime, settings.Conf.MachineID); err != nil {
@@ -55,20 +69,20 @@ func main() {
 		return
 	}
 
-	// 6. 注册路由
-	r := routers.Setup()
+	// 恢复跟踪重启前未结束的 Jenkins 构建
+	if err := service.DeployRun.Resume(); err != nil {
+		zap.L().Error("resume deploy runs failed", zap.Error(err))
+	}
 
-	// 7. 启动task
-	//go func() {
-	//	service.Event.WatchEventTask("DEV")
-	//}()
-	go func() {
-		service.Event.WatchEventTask("TST")
-	}()
+	// 6. 注册路由，并启动审计日志清理、chart 仓库刷新
+	r := routers.Setup()
+	go service.Audit.CleanTask(settings.Conf.AuditRetention)
+	go service.HelmRepo.RefreshTask(settings.Conf.HelmRepoRefresh)
 
-	// 8. websocket 启动
+	// 7. websocket 启动，/ws 为 web 终端，/ws/log 为容器实时日志
 	wsHandler := http.NewServeMux()
 	wsHandler.HandleFunc("/ws", service.Terminal.WsHandler)
+	wsHandler.HandleFunc("/ws/log", service.Pod.WsLogHandler)
 	ws := &http.Server{
 		Addr:    fmt.Sprintf(":%d", settings.Conf.WsPort),
 		Handler: wsHandler,
@@ -79,7 +93,7 @@ func main() {
 		}
 	}()
 
-	// 9. gin server 启动
+	// 8. gin server 启动
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", settings.Conf.Port),
 		Handler: r,
@@ -90,24 +104,24 @@ func main() {
 		}
 	}()
 
-	// 10. 优雅关闭server
+	// 9. 优雅关闭server
 	// 声明一个系统信号的channel，并监听他，如果没有信号，就一直阻塞，如果有，就继续执行
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 
-	// 11 设置ctx超时时间
+	// 10 设置ctx超时时间
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	//cancel用于释放ctx
 	defer cancel()
 
-	// 12 关闭 websocket
+	// 11 关闭 websocket
 	if err := ws.Shutdown(ctx); err != nil {
 		zap.L().Fatal("Websocket关闭异常:", zap.Error(err))
 	}
 	zap.L().Info("Websocket退出成功")
 
-	// 13 关闭 gin server
+	// 12 关闭 gin server
 	if err := srv.Shutdown(ctx); err != nil {
 		zap.L().Fatal("Gin Server 关闭异常：", zap.Error(err))
 	}
@@ -0,0 +1,127 @@
+package middle
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"hash"
+	"io"
+	"kubea/model"
+	"kubea/service"
+	"kubea/settings"
+	"kubea/utils"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditDigest 请求体摘要使用 HMAC-SHA256，请求体中的密码、token 等无法被离线暴力破解
+// 未配置 audit_key 时返回 nil，不记录摘要
+func auditDigest() hash.Hash {
+	if settings.Conf.AuditKey == "" {
+		return nil
+	}
+	return hmac.New(sha256.New, []byte(settings.Conf.AuditKey))
+}
+
+// Audit 记录所有非GET请求的审计日志，需在 JWTAuth 之前使用，以便记录被拒绝的请求
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		digest := auditDigest()
+		params := make(map[string]interface{})
+		if isMultipart(c) {
+			// 文件上传边读边计算摘要，不缓存整个文件
+			if digest != nil {
+				c.Request.Body = &hashReader{Reader: io.TeeReader(c.Request.Body, digest), Closer: c.Request.Body}
+			}
+		} else {
+			body := readBody(c)
+			if digest != nil {
+				digest.Write(body)
+			}
+			_ = json.Unmarshal(body, &params)
+		}
+		cluster, namespace := requestScope(c)
+
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		bodyDigest := ""
+		if digest != nil {
+			bodyDigest = hex.EncodeToString(digest.Sum(nil))
+		}
+		data := &model.AuditLog{
+			Method:       c.Request.Method,
+			Path:         path,
+			Cluster:      cluster,
+			Namespace:    namespace,
+			Resource:     auditResource(path),
+			ResourceName: auditResourceName(params),
+			BodyDigest:   bodyDigest,
+			Status:       c.Writer.Status(),
+			Duration:     time.Since(start).Milliseconds(),
+			ClientIP:     c.ClientIP(),
+		}
+		if value, exists := c.Get("claims"); exists {
+			claims := value.(*utils.CustomClaims)
+			data.UserID = claims.UserID
+			data.UserName = claims.Username
+		} else if name, exists := c.Get("webhook"); exists {
+			data.UserName = "webhook:" + name.(string)
+		} else if username, ok := params["username"].(string); ok {
+			// 登录等无需token的接口，记录请求中的用户名
+			data.UserName = username
+		}
+
+		_ = service.Audit.Add(data)
+	}
+}
+
+// hashReader 读取请求体的同时计算摘要
+type hashReader struct {
+	io.Reader
+	io.Closer
+}
+
+// auditResource 从路由中解析资源类型，如 /api/k8s/pod/del => pod
+func auditResource(path string) string {
+	path = strings.TrimPrefix(path, "/api/")
+	path = strings.TrimPrefix(path, "k8s/")
+	return strings.SplitN(path, "/", 2)[0]
+}
+
+// auditResourceName 从请求参数中解析资源名称，优先取 xxx_name，其次 release、name
+func auditResourceName(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "_name") || key == "namespace_name" || key == "container_name" {
+			continue
+		}
+		if value, ok := params[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	for _, key := range []string{"release", "name"} {
+		if value, ok := params[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
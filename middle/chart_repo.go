@@ -0,0 +1,36 @@
+package middle
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"kubea/settings"
+	"net/http"
+)
+
+// ChartRepoAuth 内置chart仓库的 basic auth，对应 helm repo add --username/--password
+// 配置 public 为 true 时允许匿名访问，未配置账号且未公开时拒绝访问
+func ChartRepoAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := settings.Conf.ChartRepo
+		if conf != nil && conf.Public {
+			c.Next()
+			return
+		}
+		if conf == nil || conf.Username == "" || conf.Password == "" {
+			c.String(http.StatusForbidden, "chart仓库未配置账号，禁止访问")
+			c.Abort()
+			return
+		}
+
+		username, password, ok := c.Request.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(conf.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(conf.Password)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="kubea charts"`)
+			c.String(http.StatusUnauthorized, "chart仓库账号或密码错误")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -2,58 +2,32 @@ package middle
 
 import (
 	"github.com/gin-gonic/gin"
-	"kubea/utils"
+	"kubea/service"
 	"net/http"
+	"strings"
 )
 
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//if len(c.Request.URL.String()) >= 10 {
-		//fmt.Println(c.Request.URL.String())
-		if len(c.Request.URL.String()) >= 10 && c.Request.URL.String()[0:10] == "/api/login" {
-			c.Next()
-		} else if len(c.Request.URL.String()) >= 12 && c.Request.URL.String()[0:12] == "/api/app/get" {
-			c.Next()
-		} else if len(c.Request.URL.String()) >= 15 && c.Request.URL.String()[0:15] == "/api/deploy/add" {
-			c.Next()
-		} else if len(c.Request.URL.String()) >= 23 && c.Request.URL.String()[0:23] == "/api/deploy/jenkniscicd" {
-			c.Next()
-		} else if len(c.Request.URL.String()) >= 22 && c.Request.URL.String()[0:22] == "/api/deploy/updatecicd" {
-			c.Next()
-		} else {
-			token := c.Request.Header.Get("Authorization")
-			if token == "" {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"msg":  "请求未携带token，无权限访问",
-					"data": nil,
-				})
-				c.Abort()
-				return
-			}
-
-			claims, err := utils.JWTToken.ParseToken(token)
-			if err != nil {
-				//token延期错误
-				if err.Error() == "TokenExpired" {
-					c.JSON(http.StatusBadRequest, gin.H{
-						"msg":  "授权已过期",
-						"data": nil,
-					})
-					c.Abort()
-					return
-				}
-				//其他解析错误
-				c.JSON(http.StatusBadRequest, gin.H{
-					"msg":  err.Error(),
-					"data": nil,
-				})
-				c.Abort()
-				return
-			}
+		// 兼容 "Bearer <token>" 格式，SSE、websocket 无法设置请求头，使用 ?token= 传递
+		token := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
 
-			// 继续交由下一个路由处理,并将解析出的信息传递下去
-			c.Set("claims", claims)
-			c.Next()
+		// 校验签名、有效期、token类型及是否已注销
+		claims, err := service.Login.Verify(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			c.Abort()
+			return
 		}
+
+		// 继续交由下一个路由处理,并将解析出的信息传递下去
+		c.Set("claims", claims)
+		c.Next()
 	}
 }
@@ -0,0 +1,58 @@
+package middle
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubea/service"
+	"kubea/utils"
+	"net/http"
+)
+
+// 登录后即可访问的接口，无需绑定权限
+var permissionWhitelist = map[string]bool{
+	"POST /api/logout": true,
+}
+
+// PermissionAuth 接口权限校验，需在 JWTAuth 之后使用
+func PermissionAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 未匹配到路由交由gin返回404
+		path := c.FullPath()
+		if path == "" {
+			c.Next()
+			return
+		}
+
+		// JWTAuth 放行的接口没有claims，不做权限校验
+		value, exists := c.Get("claims")
+		if !exists {
+			c.Next()
+			return
+		}
+		claims := value.(*utils.CustomClaims)
+
+		if permissionWhitelist[c.Request.Method+" "+path] {
+			c.Next()
+			return
+		}
+
+		allowed, err := service.ApiPermission.Check(claims.Role, c.Request.Method, path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"msg":  "无权限访问该接口",
+				"data": nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package middle
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"kubea/service"
+	"kubea/utils"
+	"net/http"
+	"strings"
+)
+
+// 集群级别的资源，只读也需要拥有整个集群(*)的权限
+var clusterResources = map[string]bool{
+	"/api/k8s/nodes":       true,
+	"/api/k8s/node/detail": true,
+	"/api/k8s/pvs":         true,
+	"/api/k8s/pv/detail":   true,
+	"/api/k8s/allres":      true,
+}
+
+// ClusterScope 校验角色对集群名称空间的访问权限，需在 JWTAuth 之后使用
+// 只读且未指定名称空间的列表请求，将可访问的名称空间写入上下文，由接口按名称空间过滤
+func ClusterScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
+		if !strings.HasPrefix(path, "/api/k8s/") && !strings.HasPrefix(path, "/api/helmstore/release") {
+			c.Next()
+			return
+		}
+
+		value, exists := c.Get("claims")
+		if !exists {
+			c.Next()
+			return
+		}
+		claims := value.(*utils.CustomClaims)
+
+		cluster, namespace := requestScope(c)
+		if cluster == "" {
+			c.Next()
+			return
+		}
+
+		write := c.Request.Method != http.MethodGet
+		if !write && namespace == "" && !clusterResources[path] {
+			namespaces, err := service.Scope.Namespaces(claims.Role, cluster)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"msg":  err.Error(),
+					"data": nil,
+				})
+				c.Abort()
+				return
+			}
+			if namespaces != nil {
+				if len(namespaces) == 0 {
+					c.JSON(http.StatusForbidden, gin.H{
+						"msg":  "无权限访问集群 " + cluster,
+						"data": nil,
+					})
+					c.Abort()
+					return
+				}
+				c.Set("namespaces", namespaces)
+			}
+			c.Next()
+			return
+		}
+
+		allowed, err := service.Scope.Check(claims.Role, cluster, namespace, write)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"msg":  "无权限访问集群 " + cluster + " 名称空间 " + namespace,
+				"data": nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requestScope 从请求参数中获取集群和名称空间，GET请求取query，其他请求取json body
+func requestScope(c *gin.Context) (cluster, namespace string) {
+	params := new(struct {
+		Cluster       string `json:"cluster" form:"cluster"`
+		Namespace     string `json:"namespace" form:"namespace"`
+		NamespaceName string `json:"namespace_name" form:"namespace_name"`
+	})
+
+	if c.Request.Method == http.MethodGet {
+		_ = c.ShouldBindQuery(params)
+	} else if !isMultipart(c) {
+		_ = json.Unmarshal(readBody(c), params)
+	}
+
+	if params.Namespace == "" {
+		params.Namespace = params.NamespaceName
+	}
+	return params.Cluster, params.Namespace
+}
+
+// readBody 读取请求体后重新写回，后续的接口仍可绑定参数
+func readBody(c *gin.Context) []byte {
+	if c.Request.Body == nil {
+		return nil
+	}
+	body, _ := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+	return body
+}
+
+// isMultipart 文件上传请求，不读取请求体
+func isMultipart(c *gin.Context) bool {
+	return strings.HasPrefix(c.ContentType(), "multipart/")
+}
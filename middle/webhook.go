@@ -0,0 +1,34 @@
+package middle
+
+import (
+	"github.com/gin-gonic/gin"
+	"kubea/service"
+	"net/http"
+)
+
+// WebhookAuth 校验外部系统的回调请求
+// 请求头 X-Kubea-Token 携带密钥，或 X-Kubea-Signature 携带 "<X-Kubea-Timestamp>.<请求体>" 的 HMAC-SHA256 签名(sha256=<hex>)
+// X-Kubea-Timestamp 为签名时的 unix 秒，超过 5 分钟的签名视为重放
+// GitLab 只支持 Secret token，使用 X-Gitlab-Token 请求头
+func WebhookAuth(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-Kubea-Token")
+		if token == "" {
+			token = c.GetHeader("X-Gitlab-Token")
+		}
+		signature := c.GetHeader("X-Kubea-Signature")
+		timestamp := c.GetHeader("X-Kubea-Timestamp")
+		if err := service.Webhook.Verify(name, token, signature, timestamp, readBody(c)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			c.Abort()
+			return
+		}
+
+		// 审计日志中记录调用方
+		c.Set("webhook", name)
+		c.Next()
+	}
+}
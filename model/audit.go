@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// AuditLog 接口操作审计记录，只记录非GET请求
+type AuditLog struct {
+	ID           uint   `json:"id" gorm:"primary_key"`
+	UserID       uint   `json:"user_id" gorm:"column:user_id"`
+	UserName     string `json:"user_name" gorm:"index"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Cluster      string `json:"cluster"`
+	Namespace    string `json:"namespace"`
+	Resource     string `json:"resource"`
+	ResourceName string `json:"resource_name"`
+	// 请求体的 HMAC-SHA256(密钥为 audit_key)，不保存原文，避免记录密码等敏感信息
+	BodyDigest string `json:"body_digest"`
+	Status     int    `json:"status"`
+	Duration   int64  `json:"duration"` // 毫秒
+	ClientIP   string `json:"client_ip" gorm:"column:client_ip"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 自定义表名
+func (*AuditLog) TableName() string {
+	return "audit_log"
+}
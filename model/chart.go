@@ -8,11 +8,15 @@ type Chart struct {
 	UpdatedAt time.Time
 	DeletedAt *time.Time `sql:"index"`
 
-	Name     string `json:"name"`
-	FileName string `json:"file_name" gorm:"column: file_name"`
-	IconUrl  string `json:"icon_url" gorm:"column: icon_url"`
-	Version  string `json:"version"`
-	Describe string `json:"describe"`
+	// 同一个 chart 可以有多个版本，Name + Version 唯一
+	Name       string `json:"name" gorm:"unique_index:idx_chart_name_version"`
+	FileName   string `json:"file_name" gorm:"column: file_name"`
+	IconUrl    string `json:"icon_url" gorm:"column: icon_url"`
+	Version    string `json:"version" gorm:"unique_index:idx_chart_name_version"`
+	AppVersion string `json:"app_version" gorm:"column:app_version"`
+	Describe   string `json:"describe"`
+	// chart 文件的 sha256，用于生成 index.yaml
+	Digest string `json:"digest"`
 }
 
 // TableName 自定义表名
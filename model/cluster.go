@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Cluster K8S 集群，Kubeconfig 和 ApiServer+Token 二选一
+type Cluster struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"unique_index"`
+	Kubeconfig  string `json:"kubeconfig,omitempty" gorm:"type:text"`
+	ApiServer   string `json:"api_server" gorm:"column:api_server"`
+	Token       string `json:"token,omitempty" gorm:"type:text"`
+	CaData      string `json:"ca_data,omitempty" gorm:"column:ca_data;type:text"`
+	Insecure    bool   `json:"insecure"`
+	Description string `json:"description"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*Cluster) TableName() string {
+	return "cluster"
+}
@@ -1,26 +1,57 @@
 package model
 
 import (
+	"fmt"
 	"time"
 )
 
+// DeployState 发布流程状态
+// [pending →] queued → code-check → building → deploying → succeeded/failed/cancelled
+type DeployState string
+
+const (
+	// DeployPending 等待审批，审批通过后进入 queued
+	DeployPending   DeployState = "pending"
+	DeployQueued    DeployState = "queued"
+	DeployCodeCheck DeployState = "code-check"
+	DeployBuilding  DeployState = "building"
+	DeployDeploying DeployState = "deploying"
+	DeploySucceeded DeployState = "succeeded"
+	DeployFailed    DeployState = "failed"
+	DeployCancelled DeployState = "cancelled"
+)
+
+// Finished 是否为结束状态
+func (s DeployState) Finished() bool {
+	return s == DeploySucceeded || s == DeployFailed || s == DeployCancelled
+}
+
+// DeployTag 是否创建 tag，沿用原有的 1/2 取值
+type DeployTag int
+
+const (
+	DeployTagCreate DeployTag = 1
+	DeployTagSkip   DeployTag = 2
+)
+
 type Deploy struct {
 	ID        uint `json:"id" gorm:"primary_key"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time `sql:"index"`
 
-	En           string `json:"en"`
-	Branch       string `json:"branch"`
-	CodeCheck    int    `json:"code_check" gorm:"column:code_check"`
-	Tag          int    `json:"tag"`
-	Status       int    `json:"status"`
-	StartTime    string `json:"start_time" gorm:"column:start_time"`
-	Duration     string `json:"duration"`
-	BuildStatus  int    `json:"build_status" gorm:"column:build_status"`
-	DeployStatus int    `json:"deploy_status" gorm:"column:deploy_status"`
-	Builder      string `json:"builder"`
-	BuildUrl     string `json:"build_url" gorm:"column:build_url"`
+	En        string    `json:"en"`
+	Branch    string    `json:"branch"`
+	Tag       DeployTag `json:"tag"`
+	StartTime string    `json:"start_time" gorm:"column:start_time"`
+	Duration  string    `json:"duration"`
+	Builder   string    `json:"builder"`
+	BuildUrl  string    `json:"build_url" gorm:"column:build_url"`
+	// 最近一次发布的状态及 run ID
+	State DeployState `json:"state"`
+	RunId int64       `json:"run_id,string" gorm:"column:run_id"`
+	// 最近一次审批的审批人
+	Approver string `json:"approver"`
 	//应用与发布数据是一对多关系
 	AppId uint `json:"app_id" gorm:"column:app_id"`
 }
@@ -29,14 +60,76 @@ func (*Deploy) TableName() string {
 	return "deploy"
 }
 
+// DeployRun 一次发布，ID 由雪花算法生成，Jenkins 回调时使用
+type DeployRun struct {
+	ID        int64 `json:"id,string" gorm:"primary_key;auto_increment:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	DeployId uint        `json:"deploy_id" gorm:"column:deploy_id;index"`
+	AppId    uint        `json:"app_id" gorm:"column:app_id"`
+	En       string      `json:"en"`
+	Branch   string      `json:"branch"`
+	Tag      DeployTag   `json:"tag"`
+	Builder  string      `json:"builder"`
+	State    DeployState `json:"state"`
+	Message  string      `json:"message" gorm:"type:text"`
+	// Jenkins 队列项及构建地址，用于跟踪构建和读取控制台输出
+	QueueUrl    string `json:"queue_url" gorm:"column:queue_url"`
+	BuildNumber int    `json:"build_number" gorm:"column:build_number"`
+	BuildUrl    string `json:"build_url" gorm:"column:build_url"`
+	// 构建产物，如镜像 tag，由 Jenkins 回调上报，晋级时原样传给下一个环境
+	Artifact string `json:"artifact"`
+	// 晋级来源的运行 ID
+	PromotedFrom int64 `json:"promoted_from,string" gorm:"column:promoted_from"`
+	// 进行中的运行为 应用ID/环境，等待审批或结束后为 NULL
+	// 唯一索引保证同一应用同一环境同时只有一个进行中的运行，多副本部署时同样生效
+	ActiveKey *string `json:"-" gorm:"column:active_key;unique_index"`
+	// 审批人及审批时间，驳回时同样记录
+	Approver   string     `json:"approver"`
+	ApprovedAt *time.Time `json:"approved_at" gorm:"column:approved_at"`
+	// 各阶段开始时间
+	QueuedAt    *time.Time `json:"queued_at" gorm:"column:queued_at"`
+	CodeCheckAt *time.Time `json:"code_check_at" gorm:"column:code_check_at"`
+	BuildingAt  *time.Time `json:"building_at" gorm:"column:building_at"`
+	DeployingAt *time.Time `json:"deploying_at" gorm:"column:deploying_at"`
+	FinishedAt  *time.Time `json:"finished_at" gorm:"column:finished_at"`
+}
+
+func (*DeployRun) TableName() string {
+	return "deploy_run"
+}
+
+// DeployActiveKey 进行中的运行的 active_key
+func DeployActiveKey(appId uint, en string) *string {
+	key := fmt.Sprintf("%d/%s", appId, en)
+	return &key
+}
+
+// 发布日志的级别及来源
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+
+	LogSourceKubea   = "kubea"
+	LogSourceJenkins = "jenkins"
+	LogSourceK8s     = "k8s"
+)
+
+// DeployLog 发布日志，每条日志一行，只追加不修改
 type DeployLog struct {
 	ID        uint `json:"id" gorm:"primary_key"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time `sql:"index"`
-	//发布日志与发布数据时一对一关系
-	DeployId uint   `json:"deploy_id" gorm:"column:deploy_id"`
-	Log      string `json:"log"`
+	//发布日志与发布数据是多对一关系
+	DeployId uint        `json:"deploy_id" gorm:"column:deploy_id;index"`
+	RunId    int64       `json:"run_id,string" gorm:"column:run_id;index"`
+	Level    string      `json:"level"`
+	Stage    DeployState `json:"stage"`
+	Source   string      `json:"source"`
+	Log      string      `json:"log" gorm:"type:text"`
 }
 
 func (*DeployLog) TableName() string {
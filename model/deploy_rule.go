@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// GitLab webhook 事件类型
+const (
+	GitlabEventPush         = "push"
+	GitlabEventTag          = "tag"
+	GitlabEventMergeRequest = "merge_request"
+)
+
+// DeployRule GitLab 事件自动发布规则，如 push 到 release/* 发布 TST，打 v* tag 发布 PROD 并需要审批
+type DeployRule struct {
+	ID        uint `json:"id" gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	AppId uint   `json:"app_id" gorm:"column:app_id;index"`
+	En    string `json:"en"`
+	// push、tag、merge_request，merge_request 只在合并时触发，匹配目标分支
+	Event string `json:"event"`
+	// 分支或 tag 名称的通配符，如 release/*、v*
+	Pattern  string    `json:"pattern"`
+	Tag      DeployTag `json:"tag"`
+	Approval bool      `json:"approval"`
+	Enabled  bool      `json:"enabled"`
+
+	Description string `json:"description"`
+}
+
+// TableName 自定义表名
+func (*DeployRule) TableName() string {
+	return "deploy_rule"
+}
@@ -6,6 +6,12 @@ type Env struct {
 	ID          uint   `json:"id" gorm:"primary_key"`
 	Name        string `json:"name" gorm:"unique;not null"`
 	Description string `json:"description"`
+	// 晋级链中的顺序，从小到大依次晋级，0 表示不在晋级链中
+	Sort int `json:"sort"`
+	// 发布到该环境是否需要审批
+	Approval bool `json:"approval"`
+	// 审批角色 ID，由 EnvApprover 维护
+	Approvers []uint `json:"approvers" gorm:"-"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -16,3 +22,18 @@ type Env struct {
 func (*Env) TableName() string {
 	return "env"
 }
+
+// EnvApprover 环境的审批角色，超级管理员始终可以审批
+type EnvApprover struct {
+	ID     uint `json:"id" gorm:"primary_key"`
+	EnvID  uint `json:"env_id" gorm:"column:env_id;index"`
+	RoleID uint `json:"role_id" gorm:"column:role_id"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*EnvApprover) TableName() string {
+	return "env_approver"
+}
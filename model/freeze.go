@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// 冻结窗口类型
+const (
+	// FreezeCron 周期性窗口，Cron 为窗口开始时间，持续 Duration 分钟
+	FreezeCron = "cron"
+	// FreezeDate 固定时间段 [StartAt, EndAt)
+	FreezeDate = "date"
+)
+
+// FreezeWindow 发布冻结窗口，En 为 * 表示所有环境
+type FreezeWindow struct {
+	ID        uint `json:"id" gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Name     string     `json:"name"`
+	En       string     `json:"en"`
+	Type     string     `json:"type"`
+	Cron     string     `json:"cron"`
+	Duration int        `json:"duration"`
+	StartAt  *time.Time `json:"start_at" gorm:"column:start_at"`
+	EndAt    *time.Time `json:"end_at" gorm:"column:end_at"`
+	Enabled  bool       `json:"enabled"`
+	// 允许紧急发布的角色 ID，由 FreezeOverride 维护，超级管理员始终可以紧急发布
+	OverrideRoles []uint `json:"override_roles" gorm:"-"`
+
+	Description string `json:"description"`
+}
+
+// TableName 自定义表名
+func (*FreezeWindow) TableName() string {
+	return "freeze_window"
+}
+
+// FreezeOverride 冻结窗口中允许紧急发布的角色
+type FreezeOverride struct {
+	ID       uint `json:"id" gorm:"primary_key"`
+	WindowID uint `json:"window_id" gorm:"column:window_id;index"`
+	RoleID   uint `json:"role_id" gorm:"column:role_id"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*FreezeOverride) TableName() string {
+	return "freeze_override"
+}
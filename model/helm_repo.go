@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// HelmRepo 远程 chart 仓库，Type 为 http(ChartMuseum/Harbor 等) 或 oci
+type HelmRepo struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"unique_index"`
+	Url         string `json:"url"`
+	Type        string `json:"type"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+	Insecure    bool   `json:"insecure"`
+	Description string `json:"description"`
+	// 最近一次刷新 index 的时间及错误信息
+	RefreshedAt  *time.Time `json:"refreshed_at" gorm:"column:refreshed_at"`
+	RefreshError string     `json:"refresh_error" gorm:"column:refresh_error;type:text"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*HelmRepo) TableName() string {
+	return "helm_repo"
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Permission 接口权限，对应 routers.Setup 中注册的路由和请求方法
+type Permission struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name"`
+	Method      string `json:"method" gorm:"unique_index:idx_method_path;not null"`
+	Path        string `json:"path" gorm:"unique_index:idx_method_path;not null"`
+	Description string `json:"description"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*Permission) TableName() string {
+	return "permission"
+}
+
+// RolePermission 角色与接口权限的绑定关系
+type RolePermission struct {
+	ID           uint `json:"id" gorm:"primary_key"`
+	RoleID       uint `json:"role_id" gorm:"column:role_id;unique_index:idx_role_permission"`
+	PermissionID uint `json:"permission_id" gorm:"column:permission_id;unique_index:idx_role_permission"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*RolePermission) TableName() string {
+	return "role_permission"
+}
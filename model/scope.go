@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 名称空间权限的访问级别，write 包含 read
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// RoleScope 角色在集群中可访问的名称空间，Namespace 为 * 表示整个集群
+type RoleScope struct {
+	ID        uint   `json:"id" gorm:"primary_key"`
+	RoleID    uint   `json:"role_id" gorm:"column:role_id"`
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Access    string `json:"access"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*RoleScope) TableName() string {
+	return "role_scope"
+}
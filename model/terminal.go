@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// TerminalRecord web 终端会话记录，录像为 asciinema v2 格式
+type TerminalRecord struct {
+	ID        int64 `json:"id,string" gorm:"primary_key;auto_increment:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UserName  string `json:"user_name" gorm:"index"`
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Command   string `json:"command"`
+	ClientIP  string `json:"client_ip" gorm:"column:client_ip"`
+	// 会话时长，单位秒
+	StartedAt *time.Time `json:"started_at" gorm:"column:started_at"`
+	EndedAt   *time.Time `json:"ended_at" gorm:"column:ended_at"`
+	Duration  int64      `json:"duration"`
+	// 录像文件名及大小
+	FileName string `json:"-" gorm:"column:file_name"`
+	Size     int64  `json:"size"`
+}
+
+// TableName 自定义表名
+func (*TerminalRecord) TableName() string {
+	return "terminal_record"
+}
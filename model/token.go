@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RevokedToken 已注销的token，过期后即可清理
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TokenID   string    `json:"token_id" gorm:"column:token_id;unique_index;not null"`
+	UserName  string    `json:"username" gorm:"column:username"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at"`
+
+	CreatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*RevokedToken) TableName() string {
+	return "revoked_token"
+}
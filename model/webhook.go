@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// 外部系统回调的集成名称
+const (
+	WebhookJenkins = "jenkins"
+	WebhookGitlab  = "gitlab"
+)
+
+// Webhook 外部系统回调的密钥，轮换后旧密钥在 PrevExpiresAt 之前仍然有效
+// 密钥不通过接口返回，只在轮换时返回一次
+type Webhook struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	Name          string     `json:"name" gorm:"unique_index"`
+	Secret        string     `json:"-"`
+	PrevSecret    string     `json:"-" gorm:"column:prev_secret"`
+	PrevExpiresAt *time.Time `json:"prev_expires_at" gorm:"column:prev_expires_at"`
+	Enabled       bool       `json:"enabled"`
+	Description   string     `json:"description"`
+	RotatedAt     *time.Time `json:"rotated_at" gorm:"column:rotated_at"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName 自定义表名
+func (*Webhook) TableName() string {
+	return "webhook"
+}
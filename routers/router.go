@@ -2,11 +2,15 @@ package routers
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"kubea/controller"
 	"kubea/logger"
 	"kubea/middle"
+	"kubea/model"
+	"kubea/service"
 	"kubea/settings"
 	"net/http"
+	"strings"
 )
 
 var Router router
@@ -19,13 +23,18 @@ func Setup() *gin.Engine {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
+	// 只信任配置的反向代理设置的客户端地址，未配置时使用连接地址，gin 默认信任所有代理
+	if err := r.SetTrustedProxies(settings.Conf.TrustedProxies); err != nil {
+		zap.L().Error("trusted_proxies 配置错误, " + err.Error())
+	}
 	// 修改日志格式
 	r.Use(logger.GinLogger(), logger.GinRecovery(true))
 	// 跨域中间件
 	r.Use(middle.Cors())
-	// JWT登陆验证中间件
-	r.Use(middle.JWTAuth())
+	// 审计日志中间件
+	r.Use(middle.Audit())
 
+	// 无需登录的接口
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"msg":  "ok",
@@ -34,8 +43,26 @@ func Setup() *gin.Engine {
 	}).GET("/version", func(c *gin.Context) {
 		c.String(http.StatusOK, settings.Conf.Version)
 	}).
-		//登录验证，路由权限信息
+		//登录验证
 		POST("/api/login", controller.Login.Auth).
+		POST("/api/token/refresh", controller.Login.Refresh)
+
+	// 内置chart仓库，供 helm repo add 使用，使用 basic auth 校验
+	r.GET("/charts/:file", middle.ChartRepoAuth(), controller.HelmStore.RepoFile)
+
+	// Jenkins 回调，使用 webhook 密钥校验
+	r.Group("/api/webhook/jenkins", middle.WebhookAuth(model.WebhookJenkins)).
+		GET("/app/get", controller.App.Get).
+		POST("/deploy/add", controller.Deploy.Add).
+		POST("/deploy/start", controller.Deploy.JenkinsCiCd).
+		POST("/deploy/update", controller.Deploy.UpdateCiCd)
+
+	// GitLab push、tag push、merge request 事件，使用 Secret token 校验
+	r.POST("/api/webhook/gitlab", middle.WebhookAuth(model.WebhookGitlab), controller.Webhook.Gitlab)
+
+	// JWT登陆验证、接口权限校验、集群名称空间权限校验
+	api := r.Group("", middle.JWTAuth(), middle.PermissionAuth(), middle.ClusterScope())
+	api.POST("/api/logout", controller.Login.Logout).
 		// 用户管理
 		GET("/api/user/list", controller.User.List).
 		POST("/api/user/add", controller.User.Add).
@@ -48,6 +75,8 @@ func Setup() *gin.Engine {
 		POST("/api/url/env/add", controller.Env.Add).
 		PUT("/api/url/env/update", controller.Env.Update).
 		DELETE("/api/url/env/del", controller.Env.Delete).
+		GET("/api/url/env/chain", controller.Env.Chain).
+		PUT("/api/url/env/policy", controller.Env.Policy).
 		// URL信息管理
 		GET("/api/url/svc/list", controller.Service.List).
 		POST("/api/url/svc/add", controller.Service.Add).
@@ -78,6 +107,28 @@ func Setup() *gin.Engine {
 		GET("/api/roleMenuRelation/getAll", controller.RoleMenuRelation.GetAll).
 		GET("/api/roleMenuRelation/getPermissions", controller.RoleMenuRelation.GetPermissions).
 		PUT("/api/roleMenuRelation/update", controller.RoleMenuRelation.Update).
+		// 接口权限管理
+		GET("/api/permission/list", controller.ApiPermission.List).
+		GET("/api/permission/getAll", controller.ApiPermission.GetAll).
+		POST("/api/permission/add", controller.ApiPermission.Add).
+		PUT("/api/permission/update", controller.ApiPermission.Update).
+		DELETE("/api/permission/del", controller.ApiPermission.Delete).
+		GET("/api/rolePermission/get", controller.ApiPermission.GetRole).
+		PUT("/api/rolePermission/update", controller.ApiPermission.UpdateRole).
+		// 名称空间权限管理
+		GET("/api/roleScope/list", controller.Scope.List).
+		POST("/api/roleScope/add", controller.Scope.Add).
+		PUT("/api/roleScope/update", controller.Scope.Update).
+		DELETE("/api/roleScope/del", controller.Scope.Delete).
+		// 审计日志
+		GET("/api/audit/list", controller.Audit.List).
+		// 终端会话记录及回放
+		GET("/api/terminal/records", controller.Terminal.Records).
+		GET("/api/terminal/record/replay", controller.Terminal.Replay).
+		// webhook 密钥管理
+		GET("/api/webhook/list", controller.Webhook.List).
+		PUT("/api/webhook/update", controller.Webhook.Update).
+		POST("/api/webhook/rotate", controller.Webhook.Rotate).
 		//应用管理
 		GET("/api/app/list", controller.App.List).
 		GET("/api/app/get", controller.App.Get).
@@ -92,11 +143,36 @@ func Setup() *gin.Engine {
 		PUT("/api/deploy/update", controller.Deploy.Update).
 		DELETE("/api/deploy/del", controller.Deploy.Delete).
 		GET("/api/deploy/getLog", controller.Deploy.GetLog).
+		GET("/api/deploy/log/stream", controller.Deploy.StreamLog).
 		POST("/api/deploy/cicd", controller.Deploy.CiCd).
-		POST("/api/deploy/jenkniscicd", controller.Deploy.JenkinsCiCd).
-		POST("/api/deploy/updatecicd", controller.Deploy.UpdateCiCd).
+		POST("/api/deploy/cancel", controller.Deploy.Cancel).
+		POST("/api/deploy/rerun", controller.Deploy.Rerun).
+		GET("/api/deploy/runs", controller.Deploy.ListRuns).
+		GET("/api/deploy/run", controller.Deploy.GetRun).
+		// 晋级及审批
+		POST("/api/deploy/promote", controller.Deploy.Promote).
+		GET("/api/deploy/approvals", controller.Deploy.Approvals).
+		POST("/api/deploy/approve", controller.Deploy.Approve).
+		POST("/api/deploy/reject", controller.Deploy.Reject).
+		// 自动发布规则
+		GET("/api/deploy/rule/list", controller.DeployRule.List).
+		POST("/api/deploy/rule/add", controller.DeployRule.Add).
+		PUT("/api/deploy/rule/update", controller.DeployRule.Update).
+		DELETE("/api/deploy/rule/del", controller.DeployRule.Delete).
+		// 发布冻结窗口
+		GET("/api/deploy/freeze/list", controller.Freeze.List).
+		GET("/api/deploy/freeze/check", controller.Freeze.Check).
+		POST("/api/deploy/freeze/add", controller.Freeze.Add).
+		PUT("/api/deploy/freeze/update", controller.Freeze.Update).
+		DELETE("/api/deploy/freeze/del", controller.Freeze.Delete).
 		//集群
 		GET("/api/k8s/clusters", controller.Cluster.GetClusters).
+		GET("/api/k8s/cluster/list", controller.Cluster.GetClusters).
+		GET("/api/k8s/cluster/health", controller.Cluster.GetHealth).
+		GET("/api/k8s/cluster/cache", controller.Cluster.GetCacheStatus).
+		POST("/api/k8s/cluster/add", controller.Cluster.Add).
+		PUT("/api/k8s/cluster/update", controller.Cluster.Update).
+		DELETE("/api/k8s/cluster/del", controller.Cluster.Delete).
 		// Pod 操作
 		GET("/api/k8s/pods", controller.Pod.GetPods).
 		GET("/api/k8s/pod/detail", controller.Pod.GetPodDetail).
@@ -104,6 +180,7 @@ func Setup() *gin.Engine {
 		PUT("/api/k8s/pod", controller.Pod.UpdatePod).
 		GET("/api/k8s/pod/container", controller.Pod.GetPodContainer).
 		GET("/api/k8s/pod/log", controller.Pod.GetPodLog).
+		GET("/api/k8s/pod/log/stream", controller.Pod.StreamPodLog).
 		// Deployment 操作
 		GET("/api/k8s/deployments", controller.Deployment.GetDeployments).
 		GET("/api/k8s/deployment/detail", controller.Deployment.GetDeploymentDetail).
@@ -169,16 +246,49 @@ func Setup() *gin.Engine {
 		GET("/api/helmstore/release/detail", controller.HelmStore.DetailRelease).
 		POST("/api/helmstore/release/install", controller.HelmStore.InstallRelease).
 		DELETE("/api/helmstore/release/uninstall", controller.HelmStore.UninstallRelease).
+		PUT("/api/helmstore/release/upgrade", controller.HelmStore.UpgradeRelease).
+		GET("/api/helmstore/release/history", controller.HelmStore.HistoryRelease).
+		POST("/api/helmstore/release/rollback", controller.HelmStore.RollbackRelease).
+		GET("/api/helmstore/release/diff", controller.HelmStore.DiffRelease).
 		GET("/api/helmstore/charts", controller.HelmStore.ListCharts).
+		GET("/api/helmstore/chart/values", controller.HelmStore.ChartValues).
 		POST("/api/helmstore/chart/add", controller.HelmStore.AddChart).
 		PUT("/api/helmstore/chart/update", controller.HelmStore.UpdateChart).
 		DELETE("/api/helmstore/chart/del", controller.HelmStore.DeleteChart).
 		POST("/api/helmstore/chartfile/upload", controller.HelmStore.UploadChartFile).
-		DELETE("/api/helmstore/chartfile/del", controller.HelmStore.DeleteChartFile)
+		DELETE("/api/helmstore/chartfile/del", controller.HelmStore.DeleteChartFile).
+		GET("/api/helmstore/repo/list", controller.HelmRepo.List).
+		POST("/api/helmstore/repo/add", controller.HelmRepo.Add).
+		PUT("/api/helmstore/repo/update", controller.HelmRepo.Update).
+		DELETE("/api/helmstore/repo/del", controller.HelmRepo.Delete).
+		POST("/api/helmstore/repo/refresh", controller.HelmRepo.Refresh).
+		GET("/api/helmstore/repo/search", controller.HelmRepo.Search)
+
+	// 路由表同步为接口权限，供角色绑定
+	if err := service.ApiPermission.Sync(permissions(r.Routes())); err != nil {
+		zap.L().Error("同步接口权限失败, " + err.Error())
+	}
 
 	return r
 }
 
+// permissions 将gin路由转换为接口权限，名称取自handler，如 pod.GetPods
+func permissions(routes gin.RoutesInfo) []*model.Permission {
+	data := make([]*model.Permission, 0, len(routes))
+	for _, route := range routes {
+		name := route.Path
+		if i := strings.LastIndex(route.Handler, ".(*"); i >= 0 {
+			name = strings.TrimSuffix(strings.Replace(route.Handler[i+3:], ")", "", 1), "-fm")
+		}
+		data = append(data, &model.Permission{
+			Name:   name,
+			Method: route.Method,
+			Path:   route.Path,
+		})
+	}
+	return data
+}
+
 //func (r *router) InitApiRouter(router *gin.Engine) {
 //	router.GET("/testapi", func(c *gin.Context) {
 //		c.JSON(http.StatusOK, gin.H{
@@ -13,119 +13,134 @@ type allRes struct{}
 
 var mt sync.Mutex
 
-func (*allRes) GetAllNum(client *kubernetes.Clientset) (map[string]int, []error) {
-	//等待所有的goroutine执行完之后，再往下执行,这里其实是阻塞的作用
-	var wg sync.WaitGroup
-	wg.Add(12)
-
-	errs := make([]error, 0)
-	//map[资源名]资源数量
-	data := make(map[string]int, 0)
+// resCounter 资源名称、缓存中的资源类型，以及缓存未同步时请求 API Server 的计数方法
+type resCounter struct {
+	name     string
+	resource string
+	count    func(client *kubernetes.Clientset) (int, error)
+}
 
-	go func() {
+var resCounters = []resCounter{
+	{"Nodes", "nodes", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-
-		//data["Nodes"] = len(list.Items)
-		//为什么要封装addMap方法？
-		//因为有12个协程会对这个map进行操作，map默认是线程非安全的，也就是所有协程一起操作map时，会有并发的报错
-		//同一时间只能有一个协程对map进行读写操作，所以addMap实际上给map加把锁
-		addMap(data, "Nodes", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Namespaces", "namespaces", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Namespaces", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Ingresses", "ingresses", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.NetworkingV1().Ingresses("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Ingresses", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"PVs", "pvs", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "PVs", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"DaemonSets", "daemonsets", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.AppsV1().DaemonSets("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "DaemonSets", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"StatefulSets", "statefulsets", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.AppsV1().StatefulSets("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "StatefulSets", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Jobs", "jobs", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.BatchV1().Jobs("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Jobs", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Services", "services", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Services", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Deployments", "deployments", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Deployments", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Pods", "pods", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Pods", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"Secrets", "secrets", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().Secrets("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "Secrets", len(list.Items))
-		wg.Done()
-	}()
-	go func() {
+		return len(list.Items), nil
+	}},
+	{"ConfigMaps", "configmaps", func(client *kubernetes.Clientset) (int, error) {
 		list, err := client.CoreV1().ConfigMaps("").List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			errs = append(errs, err)
+			return 0, err
 		}
-		addMap(data, "ConfigMaps", len(list.Items))
-		wg.Done()
-	}()
+		return len(list.Items), nil
+	}},
+}
+
+func (*allRes) GetAllNum(client *kubernetes.Clientset) (map[string]int, []error) {
+	//等待所有的goroutine执行完之后，再往下执行,这里其实是阻塞的作用
+	var wg sync.WaitGroup
+	wg.Add(len(resCounters))
+
+	errs := make([]error, 0)
+	//map[资源名]资源数量
+	data := make(map[string]int, 0)
+	rc := K8s.cache(client)
+
+	for _, counter := range resCounters {
+		go func(counter resCounter) {
+			defer wg.Done()
+			// 优先从 informer 缓存计数
+			if num, ok := cacheCount(rc, counter.resource); ok {
+				addMap(data, counter.name, num)
+				return
+			}
+			num, err := counter.count(client)
+			if err != nil {
+				mt.Lock()
+				errs = append(errs, err)
+				mt.Unlock()
+				return
+			}
+			//为什么要封装addMap方法？
+			//因为有12个协程会对这个map进行操作，map默认是线程非安全的，也就是所有协程一起操作map时，会有并发的报错
+			//同一时间只能有一个协程对map进行读写操作，所以addMap实际上给map加把锁
+			addMap(data, counter.name, num)
+		}(counter)
+	}
 	//当wg里面的计数器为0时，就取消阻塞，继续执行，非0时，则一直阻塞
 	wg.Wait()
-	return data, nil
+	return data, errs
 }
 
 func addMap(mp map[string]int, resource string, num int) {
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"time"
+)
+
+var Audit audit
+
+type audit struct{}
+
+// List 返回审计记录列表
+func (*audit) List(q *dao.AuditQuery) (*dao.AuditLogs, error) {
+	return dao.Audit.List(q)
+}
+
+// Add 记录审计日志
+func (*audit) Add(a *model.AuditLog) error {
+	return dao.Audit.Add(a)
+}
+
+// CleanTask 每天清理超过保留天数的审计记录，days 为 0 时不清理
+func (*audit) CleanTask(days int) {
+	if days <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		count, err := dao.Audit.Clean(time.Now().AddDate(0, 0, -days))
+		if err == nil && count > 0 {
+			zap.L().Info(fmt.Sprintf("清理审计记录 %d 条", count))
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"go.uber.org/zap"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+	"kubea/dao"
+	"kubea/model"
+	"sigs.k8s.io/yaml"
+)
+
+// GetChartVersion 查询内置仓库中chart的指定版本，version 为空时返回最新版本
+func (*helmStore) GetChartVersion(name, version string) (*model.Chart, error) {
+	if version != "" {
+		data, has, err := dao.Chart.HasVersion(name, version)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return nil, errors.New(fmt.Sprintf("Chart %s 版本 %s 不存在", name, version))
+		}
+		return data, nil
+	}
+
+	charts, err := dao.Chart.GetVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		latest        *model.Chart
+		latestVersion *semver.Version
+	)
+	for _, item := range charts {
+		v, err := semver.NewVersion(item.Version)
+		if err != nil {
+			continue
+		}
+		if latestVersion == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = item, v
+		}
+	}
+	if latest == nil {
+		return nil, errors.New(fmt.Sprintf("Chart %s 不存在", name))
+	}
+	return latest, nil
+}
+
+// RepoIndex 根据chart记录生成仓库的 index.yaml，chart 的地址为相对于仓库的文件名
+func (*helmStore) RepoIndex() ([]byte, error) {
+	charts, err := dao.Chart.GetVersions("")
+	if err != nil {
+		return nil, err
+	}
+
+	index := repo.NewIndexFile()
+	for _, item := range charts {
+		digest := item.Digest
+		if digest == "" {
+			path, err := chartPath(item.FileName)
+			if err == nil {
+				digest, err = provenance.DigestFile(path)
+			}
+			if err != nil {
+				zap.L().Error(fmt.Sprintf("计算chart文件 %s 摘要失败, %v", item.FileName, err))
+				continue
+			}
+		}
+		metadata := &helmchart.Metadata{
+			APIVersion:  helmchart.APIVersionV2,
+			Name:        item.Name,
+			Version:     item.Version,
+			AppVersion:  item.AppVersion,
+			Description: item.Describe,
+			Icon:        item.IconUrl,
+		}
+		if err := index.MustAdd(metadata, item.FileName, "", digest); err != nil {
+			zap.L().Error(fmt.Sprintf("chart %s 加入 index 失败, %v", item.FileName, err))
+		}
+	}
+	index.SortEntries()
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("生成 index.yaml 失败, %v", err))
+		return nil, errors.New(fmt.Sprintf("生成 index.yaml 失败, %v", err))
+	}
+	return data, nil
+}
+
+// RepoFile 返回仓库中chart文件的路径，只允许下载已登记的chart
+func (*helmStore) RepoFile(file string) (string, error) {
+	path, err := chartPath(file)
+	if err != nil {
+		return "", errors.New("chart文件不存在")
+	}
+	_, has, err := dao.Chart.HasFile(file)
+	if err != nil {
+		return "", err
+	}
+	if !has {
+		return "", errors.New("chart文件不存在")
+	}
+	return path, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"time"
+)
+
+var Cluster cluster
+
+type cluster struct{}
+
+// ClusterInfo 集群信息及健康状态，不返回凭证
+type ClusterInfo struct {
+	ID          uint           `json:"id"`
+	Name        string         `json:"name"`
+	ApiServer   string         `json:"api_server"`
+	Insecure    bool           `json:"insecure"`
+	Description string         `json:"description"`
+	Health      *ClusterHealth `json:"health"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// List 返回集群列表及最近一次探测的健康状态
+func (*cluster) List() ([]*ClusterInfo, error) {
+	clusters, err := dao.Cluster.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*ClusterInfo, 0, len(clusters))
+	for _, item := range clusters {
+		info := &ClusterInfo{
+			ID:          item.ID,
+			Name:        item.Name,
+			ApiServer:   item.ApiServer,
+			Insecure:    item.Insecure,
+			Description: item.Description,
+			CreatedAt:   item.CreatedAt,
+			UpdatedAt:   item.UpdatedAt,
+		}
+		if conf, err := K8s.GetConfig(item.Name); err == nil {
+			info.ApiServer = conf.Host
+		}
+		if health := K8s.Health(item.Name); len(health) > 0 {
+			info.Health = health[0]
+		}
+		list = append(list, info)
+	}
+
+	return list, nil
+}
+
+// Add 新增集群，并加载到 K8s.ClientMap
+func (*cluster) Add(c *model.Cluster) error {
+	if c.Name == "" {
+		return errors.New("请填写集群名称")
+	}
+	// 先校验凭证能创建 client，避免保存无法加载的集群
+	if _, _, _, err := newClient(c); err != nil {
+		return err
+	}
+	_, has, err := dao.Cluster.Has(c.Name)
+	if err != nil {
+		return err
+	}
+	if has {
+		return errors.New("该集群已存在，请重新添加")
+	}
+
+	if err := dao.Cluster.Add(c); err != nil {
+		return err
+	}
+	if err := K8s.Add(c); err != nil {
+		// 加载失败时删除已保存的集群，不留下无法使用的记录
+		if delErr := dao.Cluster.Delete(c.ID, c.Name); delErr != nil {
+			zap.L().Error(fmt.Sprintf("集群 %s 加载失败，删除记录失败, %v", c.Name, delErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// Update 更新集群，并重新加载，凭证为空时保留原值
+func (*cluster) Update(c *model.Cluster) error {
+	old, has, err := dao.Cluster.Get(c.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该集群不存在")
+	}
+	if c.Name != "" && c.Name != old.Name {
+		return errors.New("集群名称不允许修改")
+	}
+
+	if err := dao.Cluster.Update(c); err != nil {
+		return err
+	}
+	data, _, err := dao.Cluster.Get(c.ID)
+	if err != nil {
+		return err
+	}
+	return K8s.Add(data)
+}
+
+// Delete 删除集群及其名称空间权限，并卸载 client
+func (*cluster) Delete(id uint) error {
+	data, has, err := dao.Cluster.Get(id)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该集群不存在")
+	}
+
+	if err := dao.Cluster.Delete(id, data.Name); err != nil {
+		return err
+	}
+	K8s.Remove(data.Name)
+	return nil
+}
@@ -40,23 +40,22 @@ func (c *configmap) fromCells(cells []DataCell) []corev1.ConfigMap {
 }
 
 // GetConfigMaps 获取 ConfigMap 列表
-func (c *configmap) GetConfigMaps(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (configMapsResp *ConfigMapsResp, err error) {
+func (c *configmap) GetConfigMaps(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (configMapsResp *ConfigMapsResp, err error) {
 	// context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常用用法
-	cmList, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 ConfigMap 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 ConfigMap 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.ConfigMap](K8s.cache(client), "configmaps", namespace)
+	if !ok {
+		cmList, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 ConfigMap 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 ConfigMap 列表失败, %v\n", err))
+		}
+		items = cmList.Items
 	}
 	//实例化dataSelector对象，把 d 结构体中获取到的 StatefulSet 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: c.toCells(cmList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: c.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -74,6 +73,9 @@ func (c *configmap) GetConfigMaps(client *kubernetes.Clientset, filterName, name
 
 // GetConfigMapDetail 获取 ConfigMap 详情
 func (c *configmap) GetConfigMapDetail(client *kubernetes.Clientset, cmName, namespace string) (cm *corev1.ConfigMap, err error) {
+	if item, ok := cacheGet[corev1.ConfigMap](K8s.cache(client), "configmaps", namespace, cmName); ok {
+		return item, nil
+	}
 	cmDetail, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), cmName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 ConfigMap 详情失败, %v\n", err))
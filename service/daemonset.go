@@ -41,24 +41,23 @@ func (d *daemonSet) fromCells(cells []DataCell) []appsv1.DaemonSet {
 
 // GetDaemonSets 获取daemonset列表，支持过滤，排序，分页，
 // client用于选择哪个集群
-func (d *daemonSet) GetDaemonSets(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (dssResp *DaemonSetsResp, err error) {
+func (d *daemonSet) GetDaemonSets(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (dssResp *DaemonSetsResp, err error) {
 	// context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常用用法
 	//metav1.ListOptions{}用于过滤List数据，如使用label，field等
-	dsList, err := client.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 DaemonSet 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 DaemonSet 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[appsv1.DaemonSet](K8s.cache(client), "daemonsets", namespace)
+	if !ok {
+		dsList, err := client.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 DaemonSet 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 DaemonSet 列表失败, %v\n", err))
+		}
+		items = dsList.Items
 	}
 	//实例化dataSelector对象，把 d 结构体中获取到的 DaemonSet 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: d.toCells(dsList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: d.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -76,6 +75,9 @@ func (d *daemonSet) GetDaemonSets(client *kubernetes.Clientset, filterName, name
 
 // GetDaemonSetDetail 获取 DaemonSet 详情
 func (d *daemonSet) GetDaemonSetDetail(client *kubernetes.Clientset, dsName, namespace string) (ds *appsv1.DaemonSet, err error) {
+	if item, ok := cacheGet[appsv1.DaemonSet](K8s.cache(client), "daemonsets", namespace, dsName); ok {
+		return item, nil
+	}
 	dsDetail, err := client.AppsV1().DaemonSets(namespace).Get(context.TODO(), dsName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 DaemonSet 详情失败, %v\n", err))
@@ -1,14 +1,37 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	nwv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 )
 
+// 名称匹配方式
+const (
+	NameModeContains = "contains" // 包含，默认
+	NameModeIgnore   = "icase"    // 忽略大小写包含
+	NameModeRegex    = "regex"    // 正则
+)
+
+// 字段过滤和排序支持的字段
+const (
+	FieldName      = "name"
+	FieldCreation  = "creation"
+	FieldRestarts  = "restarts"
+	FieldReady     = "ready"
+	FieldPhase     = "phase"
+	FieldNodeName  = "node"
+	FieldOwnerKind = "owner"
+)
+
 // dataSelector 用于封装排序、过滤、分页的数据类型
 type dataSelector struct {
 	//当前集群[pod|svc|....]的数据
@@ -21,17 +44,43 @@ type dataSelector struct {
 type DataCell interface {
 	GetCreation() time.Time
 	GetName() string
+	GetNamespace() string
+	GetLabels() map[string]string
+	// GetField 返回 phase、node、owner 等字段的值，资源不支持该字段时返回空
+	GetField(field string) string
+	// GetMetric 返回 restarts、ready 等数值，资源不支持时返回 0
+	GetMetric(field string) int
 }
 
-// DataSelectQuery 定义过滤和分页的属性，过滤：Name，分页：Limit和page
+// DataSelectQuery 定义过滤、排序和分页的属性
 type DataSelectQuery struct {
 	FilterQuery   *FilterQuery
+	SortQuery     *SortQuery
 	PaginateQuery *PaginateQuery
 }
 
-// FilterQuery 过滤 Name
+// FilterQuery 过滤 Name，Namespaces 为 nil 时不限制名称空间
 type FilterQuery struct {
-	Name string
+	Name       string
+	NameMode   string
+	Namespaces []string
+	// 标签选择器，nil 表示不过滤
+	Selector labels.Selector
+	// 字段过滤，如 phase=Running
+	Fields map[string]string
+
+	nameRegex *regexp.Regexp
+}
+
+// SortQuery 排序，按 Keys 的顺序依次比较
+type SortQuery struct {
+	Keys []SortKey
+}
+
+// SortKey 排序字段及升降序
+type SortKey struct {
+	Field string
+	Desc  bool
 }
 
 // PaginateQuery 分页：Limit和page
@@ -40,6 +89,90 @@ type PaginateQuery struct {
 	Page  int
 }
 
+// DataSelectParams 列表接口通用的过滤、排序、分页参数
+type DataSelectParams struct {
+	FilterName    string `form:"filter_name"`
+	NameMode      string `form:"name_mode"`
+	LabelSelector string `form:"label_selector"`
+	Phase         string `form:"phase"`
+	NodeName      string `form:"node_name"`
+	OwnerKind     string `form:"owner_kind"`
+	// 排序字段，逗号分隔，如 restarts:desc,name，默认按创建时间倒序
+	SortBy string `form:"sort_by"`
+	Limit  int    `form:"limit"`
+	Page   int    `form:"page"`
+}
+
+// Query 校验参数并转换为 DataSelectQuery，namespaces 为可访问的名称空间
+func (p *DataSelectParams) Query(namespaces []string) (*DataSelectQuery, error) {
+	filter := &FilterQuery{
+		Name:       p.FilterName,
+		NameMode:   p.NameMode,
+		Namespaces: namespaces,
+		Fields:     map[string]string{},
+	}
+
+	switch p.NameMode {
+	case "", NameModeContains, NameModeIgnore:
+	case NameModeRegex:
+		reg, err := regexp.Compile(p.FilterName)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("名称正则表达式错误, %v", err))
+		}
+		filter.nameRegex = reg
+	default:
+		return nil, errors.New("name_mode 只能为 contains、icase 或 regex")
+	}
+
+	if p.LabelSelector != "" {
+		selector, err := labels.Parse(p.LabelSelector)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("标签选择器错误, %v", err))
+		}
+		filter.Selector = selector
+	}
+
+	if p.Phase != "" {
+		filter.Fields[FieldPhase] = p.Phase
+	}
+	if p.NodeName != "" {
+		filter.Fields[FieldNodeName] = p.NodeName
+	}
+	if p.OwnerKind != "" {
+		filter.Fields[FieldOwnerKind] = p.OwnerKind
+	}
+
+	sortQuery := &SortQuery{}
+	for _, item := range strings.Split(p.SortBy, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, order, _ := strings.Cut(item, ":")
+		switch field {
+		case FieldName, FieldCreation, FieldRestarts, FieldReady:
+		default:
+			return nil, errors.New("不支持的排序字段: " + field)
+		}
+		if order != "" && order != "asc" && order != "desc" {
+			return nil, errors.New("排序方式只能为 asc 或 desc")
+		}
+		sortQuery.Keys = append(sortQuery.Keys, SortKey{Field: field, Desc: order == "desc"})
+	}
+	if len(sortQuery.Keys) == 0 {
+		sortQuery.Keys = []SortKey{{Field: FieldCreation, Desc: true}}
+	}
+
+	return &DataSelectQuery{
+		FilterQuery: filter,
+		SortQuery:   sortQuery,
+		PaginateQuery: &PaginateQuery{
+			Limit: p.Limit,
+			Page:  p.Page,
+		},
+	}, nil
+}
+
 // 排序，实现自定义结构的排序，需要重写Len、Swap、Less方法
 
 // Len 方法用于获取数组长度
@@ -53,45 +186,98 @@ func (d *dataSelector) Swap(i, j int) {
 	d.GenericDataList[i], d.GenericDataList[j] = d.GenericDataList[j], d.GenericDataList[i]
 }
 
-// Less 方法用于定义数组中元素排序的“大小”的比较方式
+// Less 方法用于定义数组中元素排序的“大小”的比较方式，按排序字段依次比较
 func (d *dataSelector) Less(i, j int) bool {
-	i_tmp := d.GenericDataList[i].GetCreation()
-	j_tmp := d.GenericDataList[i].GetCreation()
-	return i_tmp.Before(j_tmp)
+	a, b := d.GenericDataList[i], d.GenericDataList[j]
+	for _, key := range d.dataSelectQuery.SortQuery.Keys {
+		result := compareCell(a, b, key.Field)
+		if result == 0 {
+			continue
+		}
+		if key.Desc {
+			return result > 0
+		}
+		return result < 0
+	}
+	return false
+}
+
+// compareCell 比较两个元素的字段，a 小于 b 返回负数
+func compareCell(a, b DataCell, field string) int {
+	switch field {
+	case FieldName:
+		return strings.Compare(a.GetName(), b.GetName())
+	case FieldCreation:
+		return a.GetCreation().Compare(b.GetCreation())
+	default:
+		return a.GetMetric(field) - b.GetMetric(field)
+	}
 }
 
-// Sort 重写以上3个方法用使用sort.Sort进行排序
+// Sort 重写以上3个方法用使用sort.Sort进行排序，排序字段相同时保持原顺序
 func (d *dataSelector) Sort() *dataSelector {
-	sort.Sort(d)
+	if d.dataSelectQuery.SortQuery == nil || len(d.dataSelectQuery.SortQuery.Keys) == 0 {
+		return d
+	}
+	sort.Stable(d)
 	return d
 }
 
-// Filter 方法用于过滤元素，比较元素的Name属性，若包含，则返回
+// Filter 方法用于过滤元素，依次比较名称、名称空间、标签和字段
 // 过滤
 func (d *dataSelector) Filter() *dataSelector {
-	// 若Name的传参为空，则返回所有元素
-	if d.dataSelectQuery.FilterQuery.Name == "" {
-		return d
+	query := d.dataSelectQuery.FilterQuery
+
+	// 有权限访问的名称空间
+	namespaces := make(map[string]bool)
+	for _, ns := range query.Namespaces {
+		namespaces[ns] = true
 	}
 
-	// 若Name的传参不为空，则返回元素中包含 Name 的所有元素覆盖默认的全部放回元素，从而达到过滤目的
 	filterdList := []DataCell{}
 	for _, value := range d.GenericDataList {
-		matched := true
-		objName := value.GetName()
-		if !strings.Contains(objName, d.dataSelectQuery.FilterQuery.Name) {
-			matched = false
+		if !query.matchName(value.GetName()) {
+			continue
+		}
+		if query.Namespaces != nil && !namespaces[value.GetNamespace()] {
+			continue
+		}
+		if query.Selector != nil && !query.Selector.Matches(labels.Set(value.GetLabels())) {
 			continue
 		}
-		if matched {
-			filterdList = append(filterdList, value)
+		matched := true
+		for field, want := range query.Fields {
+			if value.GetField(field) != want {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
 		}
+		filterdList = append(filterdList, value)
 	}
 
 	d.GenericDataList = filterdList
 	return d
 }
 
+// matchName 按匹配方式比较名称
+func (f *FilterQuery) matchName(name string) bool {
+	if f.Name == "" {
+		return true
+	}
+	switch f.NameMode {
+	case NameModeIgnore:
+		return strings.Contains(strings.ToLower(name), strings.ToLower(f.Name))
+	case NameModeRegex:
+		if f.nameRegex != nil {
+			return f.nameRegex.MatchString(name)
+		}
+	}
+	return strings.Contains(name, f.Name)
+}
+
 // Paginate 方法用于数组分页，根据Limit和Page的传参，返回数据
 func (d *dataSelector) Paginate() *dataSelector {
 	limit := d.dataSelectQuery.PaginateQuery.Limit
@@ -109,6 +295,9 @@ func (d *dataSelector) Paginate() *dataSelector {
 	startIndex := limit * (page - 1)
 	endIndex := limit * page
 
+	if startIndex > len(d.GenericDataList) {
+		startIndex = len(d.GenericDataList)
+	}
 	if len(d.GenericDataList) < endIndex {
 		endIndex = len(d.GenericDataList)
 	}
@@ -116,7 +305,20 @@ func (d *dataSelector) Paginate() *dataSelector {
 	return d
 }
 
-// podCell 定义 podCell 类型，实现两个方法GetCreation GetName，可进行类型转换
+// ownerKind 返回控制器的类型，没有控制器时返回第一个 owner 的类型
+func ownerKind(refs []metav1.OwnerReference) string {
+	for _, ref := range refs {
+		if ref.Controller != nil && *ref.Controller {
+			return ref.Kind
+		}
+	}
+	if len(refs) > 0 {
+		return refs[0].Kind
+	}
+	return ""
+}
+
+// podCell 定义 podCell 类型，实现 DataCell 接口，可进行类型转换
 // podCell 定义 podCell 实现 corev1.Pod 和 dataSelector.GenericDataList 中 DataCell 数据转化
 type podCell corev1.Pod
 
@@ -128,7 +330,43 @@ func (p podCell) GetName() string {
 	return p.Name
 }
 
-// deploymentCell  定义 deploymentCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (p podCell) GetNamespace() string {
+	return p.Namespace
+}
+
+func (p podCell) GetLabels() map[string]string {
+	return p.Labels
+}
+
+func (p podCell) GetField(field string) string {
+	switch field {
+	case FieldPhase:
+		return string(p.Status.Phase)
+	case FieldNodeName:
+		return p.Spec.NodeName
+	case FieldOwnerKind:
+		return ownerKind(p.OwnerReferences)
+	}
+	return ""
+}
+
+// GetMetric 容器重启次数之和，以及就绪的容器数
+func (p podCell) GetMetric(field string) int {
+	num := 0
+	for _, status := range p.Status.ContainerStatuses {
+		switch field {
+		case FieldRestarts:
+			num += int(status.RestartCount)
+		case FieldReady:
+			if status.Ready {
+				num++
+			}
+		}
+	}
+	return num
+}
+
+// deploymentCell  定义 deploymentCell  类型，实现 DataCell 接口，可进行类型转换
 type deploymentCell appsv1.Deployment
 
 func (d deploymentCell) GetCreation() time.Time {
@@ -139,7 +377,31 @@ func (d deploymentCell) GetName() string {
 	return d.Name
 }
 
-// daemonSetCell  定义 daemonSetCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (d deploymentCell) GetNamespace() string {
+	return d.Namespace
+}
+
+func (d deploymentCell) GetLabels() map[string]string {
+	return d.Labels
+}
+
+func (d deploymentCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(d.OwnerReferences)
+	}
+	return ""
+}
+
+func (d deploymentCell) GetMetric(field string) int {
+	switch field {
+	case FieldReady:
+		return int(d.Status.ReadyReplicas)
+	}
+	return 0
+}
+
+// daemonSetCell  定义 daemonSetCell  类型，实现 DataCell 接口，可进行类型转换
 type daemonSetCell appsv1.DaemonSet
 
 func (d daemonSetCell) GetCreation() time.Time {
@@ -150,7 +412,31 @@ func (d daemonSetCell) GetName() string {
 	return d.Name
 }
 
-// statefulSetCell  定义 statefulSetCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (d daemonSetCell) GetNamespace() string {
+	return d.Namespace
+}
+
+func (d daemonSetCell) GetLabels() map[string]string {
+	return d.Labels
+}
+
+func (d daemonSetCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(d.OwnerReferences)
+	}
+	return ""
+}
+
+func (d daemonSetCell) GetMetric(field string) int {
+	switch field {
+	case FieldReady:
+		return int(d.Status.NumberReady)
+	}
+	return 0
+}
+
+// statefulSetCell  定义 statefulSetCell  类型，实现 DataCell 接口，可进行类型转换
 type statefulSetCell appsv1.StatefulSet
 
 func (s statefulSetCell) GetCreation() time.Time {
@@ -161,7 +447,31 @@ func (s statefulSetCell) GetName() string {
 	return s.Name
 }
 
-// serviceCell  定义 serviceCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (s statefulSetCell) GetNamespace() string {
+	return s.Namespace
+}
+
+func (s statefulSetCell) GetLabels() map[string]string {
+	return s.Labels
+}
+
+func (s statefulSetCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(s.OwnerReferences)
+	}
+	return ""
+}
+
+func (s statefulSetCell) GetMetric(field string) int {
+	switch field {
+	case FieldReady:
+		return int(s.Status.ReadyReplicas)
+	}
+	return 0
+}
+
+// serviceCell  定义 serviceCell  类型，实现 DataCell 接口，可进行类型转换
 type serviceCell corev1.Service
 
 func (s serviceCell) GetCreation() time.Time {
@@ -172,7 +482,27 @@ func (s serviceCell) GetName() string {
 	return s.Name
 }
 
-// ingressCell  定义 ingressCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (s serviceCell) GetNamespace() string {
+	return s.Namespace
+}
+
+func (s serviceCell) GetLabels() map[string]string {
+	return s.Labels
+}
+
+func (s serviceCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(s.OwnerReferences)
+	}
+	return ""
+}
+
+func (s serviceCell) GetMetric(field string) int {
+	return 0
+}
+
+// ingressCell  定义 ingressCell  类型，实现 DataCell 接口，可进行类型转换
 type ingressCell nwv1.Ingress
 
 func (i ingressCell) GetCreation() time.Time {
@@ -183,7 +513,27 @@ func (i ingressCell) GetName() string {
 	return i.Name
 }
 
-// nodeCell 定义 nodeCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (i ingressCell) GetNamespace() string {
+	return i.Namespace
+}
+
+func (i ingressCell) GetLabels() map[string]string {
+	return i.Labels
+}
+
+func (i ingressCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(i.OwnerReferences)
+	}
+	return ""
+}
+
+func (i ingressCell) GetMetric(field string) int {
+	return 0
+}
+
+// nodeCell 定义 nodeCell  类型，实现 DataCell 接口，可进行类型转换
 type nodeCell corev1.Node
 
 func (n nodeCell) GetCreation() time.Time {
@@ -194,7 +544,32 @@ func (n nodeCell) GetName() string {
 	return n.Name
 }
 
-// namespaceCell 定义 namespaceCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (n nodeCell) GetNamespace() string {
+	return n.Namespace
+}
+
+func (n nodeCell) GetLabels() map[string]string {
+	return n.Labels
+}
+
+// GetField 节点的 phase 为 Ready 或 NotReady
+func (n nodeCell) GetField(field string) string {
+	if field != FieldPhase {
+		return ""
+	}
+	for _, condition := range n.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			return "Ready"
+		}
+	}
+	return "NotReady"
+}
+
+func (n nodeCell) GetMetric(field string) int {
+	return 0
+}
+
+// namespaceCell 定义 namespaceCell  类型，实现 DataCell 接口，可进行类型转换
 type namespaceCell corev1.Namespace
 
 func (n namespaceCell) GetCreation() time.Time {
@@ -205,7 +580,28 @@ func (n namespaceCell) GetName() string {
 	return n.Name
 }
 
-// pvCell 定义 pvCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+// GetNamespace Namespace 本身没有名称空间，返回自身名称用于按名称空间权限过滤
+func (n namespaceCell) GetNamespace() string {
+	return n.Name
+}
+
+func (n namespaceCell) GetLabels() map[string]string {
+	return n.Labels
+}
+
+func (n namespaceCell) GetField(field string) string {
+	switch field {
+	case FieldPhase:
+		return string(n.Status.Phase)
+	}
+	return ""
+}
+
+func (n namespaceCell) GetMetric(field string) int {
+	return 0
+}
+
+// pvCell 定义 pvCell  类型，实现 DataCell 接口，可进行类型转换
 type pvCell corev1.PersistentVolume
 
 func (p pvCell) GetCreation() time.Time {
@@ -216,7 +612,27 @@ func (p pvCell) GetName() string {
 	return p.Name
 }
 
-// configmapCell 定义 configmapCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (p pvCell) GetNamespace() string {
+	return p.Namespace
+}
+
+func (p pvCell) GetLabels() map[string]string {
+	return p.Labels
+}
+
+func (p pvCell) GetField(field string) string {
+	switch field {
+	case FieldPhase:
+		return string(p.Status.Phase)
+	}
+	return ""
+}
+
+func (p pvCell) GetMetric(field string) int {
+	return 0
+}
+
+// configmapCell 定义 configmapCell  类型，实现 DataCell 接口，可进行类型转换
 type configmapCell corev1.ConfigMap
 
 func (c configmapCell) GetCreation() time.Time {
@@ -227,7 +643,27 @@ func (c configmapCell) GetName() string {
 	return c.Name
 }
 
-// secretCell 定义 secretCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (c configmapCell) GetNamespace() string {
+	return c.Namespace
+}
+
+func (c configmapCell) GetLabels() map[string]string {
+	return c.Labels
+}
+
+func (c configmapCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(c.OwnerReferences)
+	}
+	return ""
+}
+
+func (c configmapCell) GetMetric(field string) int {
+	return 0
+}
+
+// secretCell 定义 secretCell  类型，实现 DataCell 接口，可进行类型转换
 type secretCell corev1.Secret
 
 func (c secretCell) GetCreation() time.Time {
@@ -238,7 +674,27 @@ func (c secretCell) GetName() string {
 	return c.Name
 }
 
-// pvcCell 定义 pvcCell  类型，实现两个方法 GetCreation GetName，可进行类型转换
+func (c secretCell) GetNamespace() string {
+	return c.Namespace
+}
+
+func (c secretCell) GetLabels() map[string]string {
+	return c.Labels
+}
+
+func (c secretCell) GetField(field string) string {
+	switch field {
+	case FieldOwnerKind:
+		return ownerKind(c.OwnerReferences)
+	}
+	return ""
+}
+
+func (c secretCell) GetMetric(field string) int {
+	return 0
+}
+
+// pvcCell 定义 pvcCell  类型，实现 DataCell 接口，可进行类型转换
 type pvcCell corev1.PersistentVolumeClaim
 
 func (c pvcCell) GetCreation() time.Time {
@@ -248,3 +704,25 @@ func (c pvcCell) GetCreation() time.Time {
 func (c pvcCell) GetName() string {
 	return c.Name
 }
+
+func (c pvcCell) GetNamespace() string {
+	return c.Namespace
+}
+
+func (c pvcCell) GetLabels() map[string]string {
+	return c.Labels
+}
+
+func (c pvcCell) GetField(field string) string {
+	switch field {
+	case FieldPhase:
+		return string(c.Status.Phase)
+	case FieldOwnerKind:
+		return ownerKind(c.OwnerReferences)
+	}
+	return ""
+}
+
+func (c pvcCell) GetMetric(field string) int {
+	return 0
+}
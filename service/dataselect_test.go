@@ -0,0 +1,166 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCell 测试用的 DataCell
+type testCell struct {
+	name     string
+	day      int
+	restarts int
+	ready    int
+}
+
+func (c testCell) GetCreation() time.Time {
+	return time.Date(2026, 6, c.day, 0, 0, 0, 0, time.Local)
+}
+
+func (c testCell) GetName() string {
+	return c.name
+}
+
+func (c testCell) GetNamespace() string {
+	return "default"
+}
+
+func (c testCell) GetLabels() map[string]string {
+	return nil
+}
+
+func (c testCell) GetField(field string) string {
+	return ""
+}
+
+func (c testCell) GetMetric(field string) int {
+	switch field {
+	case FieldRestarts:
+		return c.restarts
+	case FieldReady:
+		return c.ready
+	}
+	return 0
+}
+
+// testCells 按此顺序作为原始数据，用于验证排序字段相同时保持原顺序
+var testCells = []DataCell{
+	testCell{name: "api-server", day: 1, restarts: 3, ready: 1},
+	testCell{name: "Web", day: 3, restarts: 0, ready: 2},
+	testCell{name: "worker", day: 2, restarts: 3, ready: 2},
+	testCell{name: "api-gateway", day: 4, restarts: 0, ready: 1},
+}
+
+// selectNames 按参数过滤、排序、分页，返回名称列表
+func selectNames(t *testing.T, params *DataSelectParams) []string {
+	t.Helper()
+	query, err := params.Query(nil)
+	if err != nil {
+		t.Fatalf("Query(%+v) error: %v", params, err)
+	}
+	selector := &dataSelector{
+		GenericDataList: append([]DataCell{}, testCells...),
+		dataSelectQuery: query,
+	}
+	names := []string{}
+	for _, cell := range selector.Filter().Sort().Paginate().GenericDataList {
+		names = append(names, cell.GetName())
+	}
+	return names
+}
+
+func TestDataSelectSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		sortBy string
+		want   []string
+	}{
+		{"默认按创建时间倒序", "", []string{"api-gateway", "Web", "worker", "api-server"}},
+		{"名称升序-区分大小写", "name", []string{"Web", "api-gateway", "api-server", "worker"}},
+		{"名称显式升序", "name:asc", []string{"Web", "api-gateway", "api-server", "worker"}},
+		{"名称降序", "name:desc", []string{"worker", "api-server", "api-gateway", "Web"}},
+		{"多字段-重启倒序名称升序", "restarts:desc,name", []string{"api-server", "worker", "Web", "api-gateway"}},
+		{"多字段-重启升序名称倒序", "restarts,name:desc", []string{"api-gateway", "Web", "worker", "api-server"}},
+		{"多字段-就绪倒序创建时间升序", "ready:desc,creation", []string{"worker", "Web", "api-server", "api-gateway"}},
+		{"多字段-含空格", " ready:desc , creation ", []string{"worker", "Web", "api-server", "api-gateway"}},
+		// 排序字段相同时保持原顺序
+		{"稳定-重启升序", "restarts", []string{"Web", "api-gateway", "api-server", "worker"}},
+		{"稳定-重启倒序", "restarts:desc", []string{"api-server", "worker", "Web", "api-gateway"}},
+		{"稳定-就绪升序", "ready", []string{"api-server", "api-gateway", "Web", "worker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectNames(t, &DataSelectParams{SortBy: tt.sortBy})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sort_by %q = %v, want %v", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSelectName(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		mode   string
+		want   []string
+	}{
+		{"不过滤", "", "", []string{"Web", "api-gateway", "api-server", "worker"}},
+		{"默认包含", "api", "", []string{"api-gateway", "api-server"}},
+		{"包含-区分大小写", "web", NameModeContains, []string{}},
+		{"忽略大小写", "web", NameModeIgnore, []string{"Web"}},
+		{"忽略大小写-大写关键字", "API", NameModeIgnore, []string{"api-gateway", "api-server"}},
+		{"正则-分组", "^api-(server|gateway)$", NameModeRegex, []string{"api-gateway", "api-server"}},
+		{"正则-结尾", "er$", NameModeRegex, []string{"api-server", "worker"}},
+		{"正则-区分大小写", "^w", NameModeRegex, []string{"worker"}},
+		{"正则-忽略大小写", "(?i)^w", NameModeRegex, []string{"Web", "worker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectNames(t, &DataSelectParams{FilterName: tt.filter, NameMode: tt.mode, SortBy: "name"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter_name %q name_mode %q = %v, want %v", tt.filter, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSelectPaginate(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		page  int
+		want  []string
+	}{
+		{"第一页", 3, 1, []string{"Web", "api-gateway", "api-server"}},
+		{"最后一页不足", 3, 2, []string{"worker"}},
+		{"超出范围", 3, 3, []string{}},
+		{"不分页", 0, 0, []string{"Web", "api-gateway", "api-server", "worker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectNames(t, &DataSelectParams{SortBy: "name", Limit: tt.limit, Page: tt.page})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("limit %d page %d = %v, want %v", tt.limit, tt.page, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSelectQueryInvalid(t *testing.T) {
+	for _, params := range []*DataSelectParams{
+		{SortBy: "age"},
+		{SortBy: "name,age:desc"},
+		{SortBy: "phase"},
+		{SortBy: "name:up"},
+		{SortBy: "restarts:DESC"},
+		{FilterName: "(", NameMode: NameModeRegex},
+		{FilterName: "api", NameMode: "prefix"},
+		{LabelSelector: "app in (a"},
+	} {
+		if _, err := params.Query(nil); err == nil {
+			t.Errorf("Query(%+v) expected error", params)
+		}
+	}
+}
@@ -8,7 +8,8 @@ import (
 	"kubea/model"
 	"kubea/settings"
 	"kubea/utils"
-	"time"
+	"net/url"
+	"strconv"
 )
 
 var Deploy deploy
@@ -48,211 +49,456 @@ func (*deploy) Delete(id uint) error {
 	return dao.Deploy.Delete(id)
 }
 
-// CiCd 开始部署
-func (*deploy) CiCd(d *model.Deploy) error {
+// CiCd 开始部署，创建运行记录并触发 Jenkins，run ID 通过 RUN_ID 参数传给 Jenkins
+// emergency 不为空时按紧急发布处理，可在冻结窗口内发布
+func (*deploy) CiCd(d *model.Deploy, builder string, emergency *Emergency) (*model.DeployRun, error) {
+	return cicd(d, builder, emergency, nil)
+}
+
+// cicd 开始部署，from 不为空时为重新发布，沿用其构建产物及晋级来源
+func cicd(d *model.Deploy, builder string, emergency *Emergency, from *model.DeployRun) (*model.DeployRun, error) {
 	data, has, err := dao.Deploy.Get(d.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !has {
-		return errors.New("查询无此部署任务")
+		return nil, errors.New("查询无此部署任务")
+	}
+
+	// 更新分支、tag 等信息，状态由运行记录维护
+	if d.Branch != "" {
+		data.Branch = d.Branch
 	}
+	if d.Tag != 0 {
+		data.Tag = d.Tag
+	}
+	data.StartTime = ""
 
-	// 更新数据
-	d.Status = 1
-	//d.
-	err = dao.Deploy.Update(d)
+	// 需要审批的环境只创建等待审批的运行
+	approval, err := Env.RequireApproval(data.En)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// 重新发布晋级的运行时沿用原有产物，不算直接发布
+	if !approval && (from == nil || from.PromotedFrom == 0) {
+		if err := promotionOnly(data.En); err != nil {
+			return nil, err
+		}
+	}
+	var run *model.DeployRun
+	if approval {
+		run, err = DeployRun.Hold(data, builder)
+	} else {
+		run, err = DeployRun.Start(data, builder, emergency)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// 请求 jenkins 服务
-	var tag bool
-	if data.Tag == 1 {
-		tag = true
-	} else if data.Tag == 2 {
-		tag = false
+	// 重新发布晋级的运行时，直接发布原有产物，不从分支重新构建
+	if from != nil && from.Artifact != "" {
+		run.Artifact = from.Artifact
+		run.PromotedFrom = from.PromotedFrom
+		if err := dao.DeployRun.Update(run.ID, &model.DeployRun{Artifact: from.Artifact, PromotedFrom: from.PromotedFrom}); err != nil {
+			return nil, err
+		}
+		Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, fmt.Sprintf("重新发布 run %d，产物 %s", from.ID, from.Artifact))
 	}
 
-	url := fmt.Sprintf("%sbuildWithParameters?ENV=%s&BRANCH=%s&IS_CREATE_TAG=%v", data.BuildUrl, data.En, data.Branch, tag)
-	_, err = utils.CiCd(url, settings.Conf.CiCd.UserPassword)
+	if approval {
+		return run, nil
+	}
+	if err := Deploy.build(data, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
 
+// build 触发 Jenkins 参数化构建并跟踪构建状态
+func (*deploy) build(d *model.Deploy, run *model.DeployRun) error {
+	params := url.Values{
+		"ENV":           {run.En},
+		"BRANCH":        {run.Branch},
+		"IS_CREATE_TAG": {strconv.FormatBool(run.Tag == model.DeployTagCreate)},
+		"RUN_ID":        {strconv.FormatInt(run.ID, 10)},
+	}
+	// 晋级时直接发布已有产物，不重新构建
+	if run.Artifact != "" {
+		params.Set("ARTIFACT", run.Artifact)
+	}
+
+	// 请求 jenkins 服务
+	queueUrl, err := utils.NewJenkins(settings.Conf.CiCd.UserPassword).BuildWithParameters(d.BuildUrl, params)
 	if err != nil {
+		_, _ = DeployRun.Transit(run.ID, model.DeployFailed, err.Error())
 		return err
 	}
 	// 写入日志
-	Deploy.LogInfo(data.ID, "服务开始部署！！！")
+	Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, "服务开始部署！！！")
 
+	// 跟踪构建状态及控制台输出
+	if queueUrl != "" {
+		run.QueueUrl = queueUrl
+		_ = dao.DeployRun.UpdateBuild(run.ID, queueUrl, 0, "")
+		go DeployRun.Track(run.ID, queueUrl, "")
+	}
 	return nil
 }
 
-// JenkinsCiCd 开始部署
-func (*deploy) JenkinsCiCd(appId uint, en, startTime, builder string) error {
-	data, has, err := dao.Deploy.Has(en, appId)
+// Trigger 按自动发布规则发布，ref 为分支或 tag，部署任务不存在时自动创建
+// 规则需要审批时只创建等待审批的运行，不触发 Jenkins
+func (*deploy) Trigger(rule *model.DeployRule, ref, builder string) (*model.DeployRun, error) {
+	data, err := deployOf(rule.En, rule.AppId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if !has {
-		return errors.New("查询无此部署任务")
+	data.Branch = ref
+	data.Tag = rule.Tag
+	// tag 事件构建已有的 tag，不再创建
+	if rule.Event == model.GitlabEventTag || data.Tag == 0 {
+		data.Tag = model.DeployTagSkip
 	}
+	data.StartTime = ""
 
-	// 更新数据
-	data.Status = 1
-	data.StartTime = startTime
-	data.Builder = builder
+	approval, err := Env.RequireApproval(data.En)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Approval || approval {
+		return DeployRun.Hold(data, builder)
+	}
+	if err := promotionOnly(data.En); err != nil {
+		return nil, err
+	}
+	run, err := DeployRun.Start(data, builder, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := Deploy.build(data, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
 
-	err = dao.Deploy.Update(data)
+// promotionOnly 晋级链中第一个之后的环境不能直接发布，需通过 Promote 晋级，或将环境设置为需要审批
+func promotionOnly(en string) error {
+	promotion, err := Env.RequirePromotion(en)
 	if err != nil {
 		return err
 	}
-
-	// 写入日志
-	Deploy.LogInfo(data.ID, "服务开始部署！！！")
-
+	if promotion {
+		return &DeployBlockedError{
+			Reason: BlockedPromotion,
+			Msg:    fmt.Sprintf("%s 环境只能由上一个环境晋级发布", en),
+		}
+	}
 	return nil
 }
 
-// UpdateCiCd 更新CiCd流程
-func (*deploy) UpdateCiCd(en, appName, repoName, branch string, codeCheck, buildStatus, deployStatus int) error {
-	var deplayID uint
-
-	// 使用 en 和 appName 获取数据
-	deploysData, err := dao.Deploy.List(en, appName, repoName, 1, 10)
+// Promote 将发布成功的运行晋级到晋级链中的下一个环境，沿用分支、tag 及构建产物，不再创建 tag
+// to 为空时晋级到下一个环境，目标环境需要审批时只创建等待审批的运行
+func (*deploy) Promote(runId int64, to, builder string, emergency *Emergency) (*model.DeployRun, error) {
+	from, err := DeployRun.Get(runId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if from.State != model.DeploySucceeded {
+		return nil, errors.New("只能晋级发布成功的运行")
 	}
 
-	// 返回第一条记录即可
-	for _, item := range deploysData.Items {
-		deplayID = item.ID
-		break
+	next, err := Env.Next(from.En)
+	if err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = next.Name
+	}
+	if to != next.Name {
+		return nil, errors.New(fmt.Sprintf("%s 只能晋级到 %s，不能直接晋级到 %s", from.En, next.Name, to))
 	}
 
-	// 获取数据
-	deployData, _, err := dao.Deploy.Get(deplayID)
+	data, err := deployOf(to, from.AppId)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	data.Branch = from.Branch
+	data.Tag = model.DeployTagSkip
+	data.StartTime = ""
 
-	if deplayID == 0 {
-		return errors.New("查询无此应用")
+	var run *model.DeployRun
+	if next.Approval {
+		run, err = DeployRun.Hold(data, builder)
+	} else {
+		run, err = DeployRun.Start(data, builder, emergency)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// 判断 codeCheck 是否 != 0
-	if codeCheck != 0 {
-		deployData.CodeCheck = codeCheck
-		if codeCheck == 2 {
-			deployData.Status = 4
-			// 部署耗时
-			deployData.Duration = Deploy.getDuration(deployData.StartTime)
-			// 更新日志
-			Deploy.LogInfo(deployData.ID, "代码检查失败！代码 checkout 未通过，当前分支存在未合并代码")
-		}
+	// 记录晋级来源及产物
+	run.PromotedFrom = from.ID
+	run.Artifact = from.Artifact
+	if err := dao.DeployRun.Update(run.ID, &model.DeployRun{PromotedFrom: from.ID, Artifact: from.Artifact}); err != nil {
+		return nil, err
 	}
+	Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, fmt.Sprintf("由 %s 环境的 run %d 晋级，分支 %s，产物 %s", from.En, from.ID, from.Branch, from.Artifact))
 
-	// 判断 buildStatus 是否 == 1
-	if buildStatus == 1 {
-		deployData.BuildStatus = buildStatus
-		deployData.Status = 2
-		// 更新日志
-		Deploy.LogInfo(deployData.ID, "服务开始编译！！！")
-	} else if buildStatus == 2 {
-		deployData.BuildStatus = buildStatus
-		deployData.Status = 4
-		// 部署耗时
-		deployData.Duration = Deploy.getDuration(deployData.StartTime)
-		// 更新日志
-		Deploy.LogInfo(deployData.ID, "服务编译失败！！！")
-	}
-
-	// 判断 deployStatus 是否 == 2
-	if deployStatus == 1 && deployData.Status == 2 {
-		deployData.DeployStatus = deployStatus
-		deployData.Status = 3
-		// 程序启动日志查看
-		// 部署耗时
-		deployData.Duration = Deploy.getDuration(deployData.StartTime)
-		// 更新日志
-		Deploy.LogInfo(deployData.ID, "服务开始部署！！！")
-	} else if deployStatus == 2 {
-		deployData.DeployStatus = deployStatus
-		deployData.Status = 4
-		// 部署耗时
-		deployData.Duration = Deploy.getDuration(deployData.StartTime)
-		// 更新日志
-		Deploy.LogInfo(deployData.ID, "服务部署失败！！！")
-	}
-
-	// 更新 Tag 信息
-	if len(branch) > 0 {
-		deployData.Branch = branch
-	}
-
-	// 更新 Tag 信息
-	if len(branch) > 0 {
-		deployData.Branch = branch
-	}
-
-	// 更新服务
-	return dao.Deploy.Update(deployData)
+	if next.Approval {
+		return run, nil
+	}
+	if err := Deploy.build(data, run); err != nil {
+		return nil, err
+	}
+	return run, nil
 }
 
-// getDuration 获取时间，计算耗时
-func (*deploy) getDuration(startTimeStr string) string {
-	// 解析开始时间
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-
-	startTime, err := time.ParseInLocation("2006-01-02 15:04:05", startTimeStr, loc)
+// Approve 审批通过等待审批的运行并触发 Jenkins，审批人的角色需为目标环境的审批角色，且不能审批自己发起的发布
+func (*deploy) Approve(runId int64, approver string, roleID uint, emergency *Emergency) (*model.DeployRun, error) {
+	run, err := approvable(runId, approver, roleID)
 	if err != nil {
-		zap.L().Error(err.Error())
-		return ""
+		return nil, err
+	}
+	data, has, err := dao.Deploy.Get(run.DeployId)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("查询无此部署任务")
 	}
 
-	// 获取当前时间作为结束时间
-	endTime := time.Now()
+	if run, err = DeployRun.Approve(run, approver, emergency); err != nil {
+		return nil, err
+	}
+	if err := Deploy.build(data, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
 
-	// 计算时间差
-	duration := endTime.Sub(startTime)
-	// 使用 String 方法将时间差格式化为分秒形式
-	durationString := duration.Truncate(time.Second).String()
+// Reject 驳回等待审批的运行
+func (*deploy) Reject(runId int64, approver string, roleID uint, reason string) (*model.DeployRun, error) {
+	run, err := approvable(runId, approver, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return DeployRun.Reject(run, approver, reason)
+}
 
-	// 打印时间差
-	return durationString
+// DeployForbiddenError 无权审批或审批自己发起的发布，接口据此返回 403
+type DeployForbiddenError struct {
+	Msg string
 }
 
-// GetLog 查询日志
-func (*deploy) GetLog(deployId uint) (*model.DeployLog, bool, error) {
-	return dao.Deploy.GetLog(deployId)
+func (e *DeployForbiddenError) Error() string {
+	return e.Msg
 }
 
-// LogInfo 写日志
-func (*deploy) LogInfo(deployId uint, content string) {
-	// 查日志
-	data, has, err := dao.Deploy.GetLog(deployId)
+// approvable 查询待审批的运行，并校验角色能否审批，发起人不能审批自己的发布
+func approvable(runId int64, approver string, roleID uint) (*model.DeployRun, error) {
+	run, err := DeployRun.Get(runId)
 	if err != nil {
-		zap.L().Error("日志写入报错：", zap.Error(err))
+		return nil, err
+	}
+	if run.State != model.DeployPending {
+		return nil, errors.New("该发布不在待审批状态")
+	}
+	if run.Builder == approver {
+		return nil, &DeployForbiddenError{Msg: "不能审批自己发起的发布"}
+	}
+	ok, err := Env.CanApprove(roleID, run.En)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &DeployForbiddenError{Msg: fmt.Sprintf("无权审批 %s 环境的发布", run.En)}
+	}
+	return run, nil
+}
+
+// deployOf 查询应用在环境中的部署任务，不存在时自动创建
+func deployOf(en string, appId uint) (*model.Deploy, error) {
+	data, has, err := dao.Deploy.Has(en, appId)
+	if err != nil {
+		return nil, err
 	}
-	// 若存在就修改
 	if has {
-		logContent := fmt.Sprintf("%s%s\n", data.Log, content)
-		data.Log = logContent
-		err = dao.Deploy.UpdateLog(data)
-		if err != nil {
-			zap.L().Error(err.Error())
+		return data, nil
+	}
+	data = &model.Deploy{En: en, AppId: appId}
+	if err := Deploy.Add(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// JenkinsCiCd 由 Jenkins 发起的部署，创建运行记录，返回的 run ID 用于后续回调
+// buildUrl 为当前构建地址，不为空时跟踪构建状态及控制台输出
+// Jenkins 中已开始构建，无法等待审批，需要审批的环境直接拒绝，应通过 kubea 发起
+func (*deploy) JenkinsCiCd(appId uint, en, startTime, builder, buildUrl string) (*model.DeployRun, error) {
+	data, has, err := dao.Deploy.Has(en, appId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		return nil, errors.New("查询无此部署任务")
+	}
+
+	approval, err := Env.RequireApproval(en)
+	if err != nil {
+		return nil, err
+	}
+	if approval {
+		return nil, &DeployBlockedError{
+			Reason: BlockedApproval,
+			Msg:    fmt.Sprintf("%s 环境的发布需要审批，不能由 Jenkins 直接发起", en),
 		}
-	} else {
-		// 若不存在就新增
-		logContent := fmt.Sprintf("%s\n", content)
+	}
+	if err := promotionOnly(en); err != nil {
+		return nil, err
+	}
 
-		err = dao.Deploy.AddLog(&model.DeployLog{
-			DeployId: deployId,
-			Log:      logContent,
-		})
+	data.StartTime = startTime
+	run, err := DeployRun.Start(data, builder, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 写入日志
+	Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, "服务开始部署！！！")
+
+	if buildUrl != "" {
+		run.BuildUrl = buildUrl
+		_ = dao.DeployRun.UpdateBuild(run.ID, "", 0, buildUrl)
+		go DeployRun.Track(run.ID, "", buildUrl)
+	}
+
+	return run, nil
+}
+
+// UpdateCiCd Jenkins 回调，按 run ID 迁移发布状态，artifact 为构建产物，晋级时使用
+func (*deploy) UpdateCiCd(runId int64, state model.DeployState, branch, message, artifact string) error {
+	run, err := DeployRun.Transit(runId, state, message)
+	if err != nil {
+		return err
+	}
+
+	if artifact != "" && artifact != run.Artifact {
+		if err := dao.DeployRun.Update(runId, &model.DeployRun{Artifact: artifact}); err != nil {
+			return err
+		}
+	}
+
+	// 更新分支信息
+	if len(branch) > 0 && branch != run.Branch {
+		d, has, err := dao.Deploy.Get(run.DeployId)
 		if err != nil {
-			zap.L().Error(err.Error())
+			return err
+		}
+		if has {
+			d.Branch = branch
+			return dao.Deploy.Update(d)
 		}
 	}
+	return nil
+}
+
+// Cancel 取消发布任务进行中或等待审批的运行，中止 Jenkins 构建或取消排队
+// runId 为 0 时取消最近一次运行
+func (*deploy) Cancel(deployId uint, runId int64, operator string) (*model.DeployRun, error) {
+	data, has, err := dao.Deploy.Get(deployId)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("查询无此部署任务")
+	}
+	if runId == 0 {
+		runId = data.RunId
+	}
+	if runId == 0 {
+		return nil, errors.New("该部署任务没有进行中的发布")
+	}
+	run, err := DeployRun.Get(runId)
+	if err != nil {
+		return nil, err
+	}
+	if run.DeployId != data.ID {
+		return nil, errors.New("发布记录不属于该部署任务")
+	}
+	if run.State.Finished() {
+		return nil, errors.New("该部署任务没有进行中的发布")
+	}
+
+	// 只有通过回调上报状态的运行，没有 Jenkins 地址，直接标记取消
+	jenkins := utils.NewJenkins(settings.Conf.CiCd.UserPassword)
+	if run.BuildUrl != "" {
+		err = jenkins.Stop(run.BuildUrl)
+	} else if run.QueueUrl != "" {
+		err = jenkins.CancelQueue(run.QueueUrl)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return DeployRun.Transit(run.ID, model.DeployCancelled, fmt.Sprintf("由 %s 取消", operator))
+}
 
+// Rerun 重新发布，沿用指定运行的分支、tag 及构建产物，runId 为 0 时使用最近一次运行
+func (*deploy) Rerun(deployId uint, runId int64, builder string, emergency *Emergency) (*model.DeployRun, error) {
+	data, has, err := dao.Deploy.Get(deployId)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("查询无此部署任务")
+	}
+	if runId == 0 {
+		runId = data.RunId
+	}
+	if runId == 0 {
+		return nil, errors.New("该部署任务还没有发布记录")
+	}
+
+	run, err := DeployRun.Get(runId)
+	if err != nil {
+		return nil, err
+	}
+	if run.DeployId != data.ID {
+		return nil, errors.New("发布记录不属于该部署任务")
+	}
+
+	return cicd(&model.Deploy{
+		ID:     data.ID,
+		Branch: run.Branch,
+		Tag:    run.Tag,
+	}, builder, emergency, run)
+}
+
+// GetLog 查询日志，支持分页及按 since 增量查询
+func (*deploy) GetLog(q *dao.DeployLogQuery) (*dao.DeployLogs, error) {
+	return dao.Deploy.ListLogs(q)
+}
+
+// Log 追加一条发布日志，并推送给实时查看日志的订阅者
+// Track 与 Jenkins 回调可能同时写入，同一发布任务的日志串行写入
+func (*deploy) Log(run *model.DeployRun, level, source, content string) {
+	data := &model.DeployLog{
+		DeployId: run.DeployId,
+		RunId:    run.ID,
+		Level:    level,
+		Stage:    run.State,
+		Source:   source,
+		Log:      content,
+	}
+	writer := deployLogHub.writer(run.DeployId)
+	writer.Lock()
+	defer writer.Unlock()
+	if err := dao.Deploy.AddLog(data); err != nil {
+		zap.L().Error("日志写入报错：", zap.Error(err))
+		return
+	}
+	deployLogHub.Publish(run.DeployId, data)
 }
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"kubea/settings"
+	"kubea/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jenkinsPollInterval 轮询 Jenkins 队列、构建及控制台输出的间隔
+	jenkinsPollInterval = 2 * time.Second
+	// jenkinsMaxFailures 连续请求失败的次数上限，超过后结束跟踪
+	jenkinsMaxFailures = 30
+)
+
+var deployLogHub = &logHub{
+	subs:    make(map[uint]map[chan *model.DeployLog]struct{}),
+	active:  make(map[uint]int),
+	writers: make(map[uint]*sync.Mutex),
+}
+
+// logHub 发布日志的订阅者，按发布任务 ID 分组，用于实时查看构建日志
+type logHub struct {
+	mu   sync.Mutex
+	subs map[uint]map[chan *model.DeployLog]struct{}
+	// 正在跟踪构建的数量，为 0 时不会再有新的输出
+	active map[uint]int
+	// 同一发布任务的日志逐条写入，保证提交顺序与自增 ID 一致，增量查询按 ID 作为游标不会遗漏
+	writers map[uint]*sync.Mutex
+}
+
+// writer 返回发布任务的日志写锁
+func (h *logHub) writer(deployId uint) *sync.Mutex {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.writers[deployId] == nil {
+		h.writers[deployId] = new(sync.Mutex)
+	}
+	return h.writers[deployId]
+}
+
+// Subscribe 订阅发布日志，返回是否仍有构建在跟踪
+func (h *logHub) Subscribe(deployId uint) (chan *model.DeployLog, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan *model.DeployLog, 256)
+	if h.subs[deployId] == nil {
+		h.subs[deployId] = make(map[chan *model.DeployLog]struct{})
+	}
+	h.subs[deployId][ch] = struct{}{}
+	return ch, h.active[deployId] > 0
+}
+
+// Unsubscribe 取消订阅
+func (h *logHub) Unsubscribe(deployId uint, ch chan *model.DeployLog) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[deployId][ch]; ok {
+		delete(h.subs[deployId], ch)
+		close(ch)
+	}
+}
+
+// Publish 推送日志，订阅者处理不过来时丢弃
+func (h *logHub) Publish(deployId uint, data *model.DeployLog) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[deployId] {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// start 开始跟踪构建
+func (h *logHub) start(deployId uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.active[deployId]++
+}
+
+// done 结束跟踪构建，没有其他构建时关闭所有订阅
+func (h *logHub) done(deployId uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.active[deployId]--; h.active[deployId] > 0 {
+		return
+	}
+	delete(h.active, deployId)
+	for ch := range h.subs[deployId] {
+		close(ch)
+	}
+	delete(h.subs, deployId)
+}
+
+// Track 跟踪 Jenkins 构建：等待队列项开始构建，持续读取控制台输出写入发布日志，构建结束后更新运行状态
+// queueUrl 和 buildUrl 二选一，由 Jenkins 发起的部署直接传入 buildUrl
+func (*deployRun) Track(id int64, queueUrl, buildUrl string) {
+	run, err := DeployRun.Get(id)
+	if err != nil {
+		return
+	}
+	deployLogHub.start(run.DeployId)
+	defer deployLogHub.done(run.DeployId)
+
+	jenkins := utils.NewJenkins(settings.Conf.CiCd.UserPassword)
+	failures := 0
+
+	// 等待队列项开始构建
+	for buildUrl == "" {
+		time.Sleep(jenkinsPollInterval)
+		item, err := jenkins.QueueItem(queueUrl)
+		if err != nil {
+			if failures++; failures > jenkinsMaxFailures {
+				_, _ = DeployRun.Transit(id, model.DeployFailed, "无法获取 Jenkins 队列状态, "+err.Error())
+				return
+			}
+			continue
+		}
+		failures = 0
+		if item.Cancelled {
+			_, _ = DeployRun.Transit(id, model.DeployCancelled, "Jenkins 队列已取消")
+			return
+		}
+		if item.Executable != nil {
+			buildUrl = item.Executable.Url
+			_ = dao.DeployRun.UpdateBuild(id, "", item.Executable.Number, buildUrl)
+		}
+	}
+	Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, fmt.Sprintf("Jenkins 开始构建 %s", buildUrl))
+
+	// 读取控制台输出，按行写入发布日志，直到构建结束，pending 为未读完的半行
+	// 重启后恢复跟踪时从头读取，跳过已经写入的行
+	var (
+		start   int64
+		pending string
+	)
+	skip, err := dao.Deploy.CountLogs(id, model.LogSourceJenkins)
+	for err != nil {
+		if failures++; failures > jenkinsMaxFailures {
+			_, _ = DeployRun.Transit(id, model.DeployFailed, "无法读取已有发布日志, "+err.Error())
+			return
+		}
+		time.Sleep(jenkinsPollInterval)
+		skip, err = dao.Deploy.CountLogs(id, model.LogSourceJenkins)
+	}
+	failures = 0
+	for {
+		text, next, more, err := jenkins.ProgressiveText(buildUrl, start)
+		if err != nil {
+			if failures++; failures > jenkinsMaxFailures {
+				_, _ = DeployRun.Transit(id, model.DeployFailed, "无法获取 Jenkins 控制台输出, "+err.Error())
+				return
+			}
+			time.Sleep(jenkinsPollInterval)
+			continue
+		}
+		failures = 0
+		if latest, err := DeployRun.Get(id); err == nil {
+			run = latest
+		}
+		lines := strings.Split(pending+text, "\n")
+		pending = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			Deploy.Log(run, model.LogLevelInfo, model.LogSourceJenkins, strings.TrimSuffix(line, "\r"))
+		}
+		start = next
+		if !more {
+			break
+		}
+		time.Sleep(jenkinsPollInterval)
+	}
+	if pending != "" && skip == 0 {
+		Deploy.Log(run, model.LogLevelInfo, model.LogSourceJenkins, pending)
+	}
+
+	// 根据构建结果结束运行，控制台输出结束后 post 步骤可能仍在执行，等待构建结束且有结果
+	for {
+		build, err := jenkins.Build(buildUrl)
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("查询 Jenkins 构建 %s 失败, %v", buildUrl, err))
+			if failures++; failures > jenkinsMaxFailures {
+				_, _ = DeployRun.Transit(id, model.DeployFailed, "无法获取 Jenkins 构建结果, "+err.Error())
+				return
+			}
+			time.Sleep(jenkinsPollInterval)
+			continue
+		}
+		failures = 0
+		if build.Building || build.Result == "" {
+			// Jenkins 已回调结束状态时不再等待
+			if latest, err := DeployRun.Get(id); err == nil && latest.State.Finished() {
+				return
+			}
+			time.Sleep(jenkinsPollInterval)
+			continue
+		}
+		_ = dao.DeployRun.UpdateBuild(id, "", build.Number, build.Url)
+		DeployRun.finish(id, build.Result)
+		return
+	}
+}
+
+// Resume 服务重启后恢复跟踪未结束的构建，跟踪只在内存中进行，否则这些运行不会结束，并一直占用 应用+环境
+func (*deployRun) Resume() error {
+	runs, err := dao.DeployRun.Unfinished()
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		zap.L().Info(fmt.Sprintf("恢复跟踪发布 run %d, %s", run.ID, run.State))
+		go DeployRun.Track(run.ID, run.QueueUrl, run.BuildUrl)
+	}
+	return nil
+}
+
+// finish 按 Jenkins 构建结果结束运行，Jenkins 已回调结束状态时不处理
+func (*deployRun) finish(id int64, result string) {
+	run, err := DeployRun.Get(id)
+	if err != nil || run.State.Finished() {
+		return
+	}
+
+	switch result {
+	case "SUCCESS":
+		// Jenkins 未回调中间阶段时，依次迁移到 succeeded
+		for _, state := range []model.DeployState{model.DeployBuilding, model.DeployDeploying, model.DeploySucceeded} {
+			if !canTransit(run.State, state) {
+				continue
+			}
+			if run, err = DeployRun.Transit(id, state, ""); err != nil {
+				return
+			}
+		}
+	case "ABORTED":
+		_, _ = DeployRun.Transit(id, model.DeployCancelled, "Jenkins 构建已中止")
+	default:
+		_, _ = DeployRun.Transit(id, model.DeployFailed, fmt.Sprintf("Jenkins 构建结果 %s", result))
+	}
+}
+
+// SubscribeLog 订阅发布日志，返回新日志的 channel 以及是否仍有构建在跟踪
+// channel 只作为有新日志的通知，订阅者处理不过来时会丢弃，日志内容以 LogsSince 从数据库读取为准
+func (*deploy) SubscribeLog(deployId uint) (chan *model.DeployLog, bool) {
+	return deployLogHub.Subscribe(deployId)
+}
+
+// LogsSince 分页读取 since 之后的全部日志，直到返回不足一页，返回最后一条日志的 ID
+func (*deploy) LogsSince(deployId uint, since uint, fn func(*model.DeployLog)) (uint, error) {
+	const pageSize = 1000
+	for {
+		data, err := dao.Deploy.ListLogs(&dao.DeployLogQuery{DeployId: deployId, Since: since, Limit: pageSize})
+		if err != nil {
+			return since, err
+		}
+		for _, item := range data.Items {
+			fn(item)
+		}
+		since = data.Cursor
+		if len(data.Items) < pageSize {
+			return since, nil
+		}
+	}
+}
+
+// UnsubscribeLog 取消订阅发布日志
+func (*deploy) UnsubscribeLog(deployId uint, ch chan *model.DeployLog) {
+	deployLogHub.Unsubscribe(deployId, ch)
+}
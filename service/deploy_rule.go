@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"kubea/dao"
+	"kubea/model"
+	"path"
+)
+
+var DeployRule deployRule
+
+type deployRule struct{}
+
+// List 查询应用的自动发布规则，appId 为 0 时查询所有
+func (*deployRule) List(appId uint) ([]*model.DeployRule, error) {
+	return dao.DeployRule.List(appId)
+}
+
+// Add 新增
+func (*deployRule) Add(r *model.DeployRule) error {
+	if err := validateDeployRule(r); err != nil {
+		return err
+	}
+	return dao.DeployRule.Add(r)
+}
+
+// Update 更新
+func (*deployRule) Update(r *model.DeployRule) error {
+	data, has, err := dao.DeployRule.Get(r.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("查询无此发布规则")
+	}
+	if err := validateDeployRule(r); err != nil {
+		return err
+	}
+
+	data.AppId = r.AppId
+	data.En = r.En
+	data.Event = r.Event
+	data.Pattern = r.Pattern
+	data.Tag = r.Tag
+	data.Approval = r.Approval
+	data.Enabled = r.Enabled
+	data.Description = r.Description
+	return dao.DeployRule.Save(data)
+}
+
+// Delete 删除
+func (*deployRule) Delete(id uint) error {
+	return dao.DeployRule.Delete(id)
+}
+
+// Match 返回应用中与事件及分支、tag 匹配的规则
+func (*deployRule) Match(appId uint, event, ref string) ([]*model.DeployRule, error) {
+	rules, err := dao.DeployRule.Match(appId, event)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]*model.DeployRule, 0, len(rules))
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Pattern, ref); ok {
+			data = append(data, rule)
+		}
+	}
+	return data, nil
+}
+
+// validateDeployRule 校验应用、环境、事件类型及通配符
+func validateDeployRule(r *model.DeployRule) error {
+	if _, has, err := dao.App.Get(r.AppId); err != nil {
+		return err
+	} else if !has {
+		return errors.New("查询无此应用")
+	}
+	if _, has, err := dao.Env.Has(r.En); err != nil {
+		return err
+	} else if !has {
+		return errors.New(fmt.Sprintf("环境 %s 不存在", r.En))
+	}
+
+	switch r.Event {
+	case model.GitlabEventPush, model.GitlabEventTag, model.GitlabEventMergeRequest:
+	default:
+		return errors.New(fmt.Sprintf("不支持的事件类型 %s，只支持 push、tag、merge_request", r.Event))
+	}
+	if r.Pattern == "" {
+		return errors.New("分支或 tag 通配符不能为空")
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return errors.New(fmt.Sprintf("通配符 %s 格式错误", r.Pattern))
+	}
+	if r.Tag != 0 && r.Tag != model.DeployTagCreate && r.Tag != model.DeployTagSkip {
+		return errors.New("tag 只能为 1(创建) 或 2(不创建)")
+	}
+	return nil
+}
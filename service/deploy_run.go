@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"kubea/dao"
+	"kubea/middle/snowflake"
+	"kubea/model"
+	"time"
+)
+
+var DeployRun deployRun
+
+type deployRun struct{}
+
+// deployTransitions 合法的状态迁移，代码检查阶段可以跳过
+var deployTransitions = map[model.DeployState][]model.DeployState{
+	model.DeployPending:   {model.DeployQueued, model.DeployCancelled},
+	model.DeployQueued:    {model.DeployCodeCheck, model.DeployBuilding, model.DeployFailed, model.DeployCancelled},
+	model.DeployCodeCheck: {model.DeployBuilding, model.DeployFailed, model.DeployCancelled},
+	model.DeployBuilding:  {model.DeployDeploying, model.DeployFailed, model.DeployCancelled},
+	model.DeployDeploying: {model.DeploySucceeded, model.DeployFailed, model.DeployCancelled},
+}
+
+// deployStageColumns 各状态对应的阶段时间字段
+var deployStageColumns = map[model.DeployState]string{
+	model.DeployQueued:    "queued_at",
+	model.DeployCodeCheck: "code_check_at",
+	model.DeployBuilding:  "building_at",
+	model.DeployDeploying: "deploying_at",
+	model.DeploySucceeded: "finished_at",
+	model.DeployFailed:    "finished_at",
+	model.DeployCancelled: "finished_at",
+}
+
+// deployStageLogs 各状态写入发布日志的内容
+var deployStageLogs = map[model.DeployState]string{
+	model.DeployPending:   "等待审批！！！",
+	model.DeployQueued:    "审批通过，开始排队！！！",
+	model.DeployCodeCheck: "代码检查中！！！",
+	model.DeployBuilding:  "服务开始编译！！！",
+	model.DeployDeploying: "服务开始部署！！！",
+	model.DeploySucceeded: "服务部署成功！！！",
+	model.DeployFailed:    "服务部署失败！！！",
+	model.DeployCancelled: "服务部署已取消！！！",
+}
+
+// canTransit 判断状态能否迁移
+func canTransit(from, to model.DeployState) bool {
+	for _, item := range deployTransitions[from] {
+		if item == to {
+			return true
+		}
+	}
+	return false
+}
+
+// List 发布的运行记录
+func (*deployRun) List(deployId uint, page, limit int) (*dao.DeployRuns, error) {
+	return dao.DeployRun.List(deployId, page, limit)
+}
+
+// Get 查询运行记录
+func (*deployRun) Get(id int64) (*model.DeployRun, error) {
+	data, has, err := dao.DeployRun.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("查询无此发布记录")
+	}
+	return data, nil
+}
+
+// Start 为发布任务创建一次运行，状态为 queued，同一应用同一环境同时只允许一个运行
+// 环境处于冻结窗口时拒绝，emergency 不为空时按紧急发布处理
+func (r *deployRun) Start(d *model.Deploy, builder string, emergency *Emergency) (*model.DeployRun, error) {
+	return r.create(d, builder, model.DeployQueued, emergency)
+}
+
+// Hold 为发布任务创建一次等待审批的运行，审批通过前不占用 应用+环境，也不同步到发布任务
+func (r *deployRun) Hold(d *model.Deploy, builder string) (*model.DeployRun, error) {
+	run, err := r.create(d, builder, model.DeployPending, nil)
+	if err != nil {
+		return nil, err
+	}
+	Deploy.Log(run, model.LogLevelInfo, model.LogSourceKubea, deployStageLogs[model.DeployPending])
+	return run, nil
+}
+
+// Approve 审批通过，运行进入 queued，与 Start 一样同一应用同一环境同时只允许一个运行，并校验冻结窗口
+func (r *deployRun) Approve(run *model.DeployRun, approver string, emergency *Emergency) (*model.DeployRun, error) {
+	notice, err := guard(run.AppId, run.En, emergency)
+	if err != nil {
+		return nil, err
+	}
+	if run.State != model.DeployPending {
+		return nil, errors.New("该发布不在待审批状态")
+	}
+	// 先迁移状态，被锁时不记录审批人，仍可再次审批
+	if run, err = DeployRun.Transit(run.ID, model.DeployQueued, fmt.Sprintf("审批人 %s", approver)); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	run.Approver = approver
+	run.ApprovedAt = &now
+	if err := dao.DeployRun.Update(run.ID, &model.DeployRun{Approver: approver, ApprovedAt: &now}); err != nil {
+		return nil, err
+	}
+
+	// 审批通过后才成为发布任务的最近一次运行
+	d, has, err := dao.Deploy.Get(run.DeployId)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("查询无此部署任务")
+	}
+	d.Approver = approver
+	d.StartTime = ""
+	if err := attach(d, run); err != nil {
+		return nil, err
+	}
+	if notice != "" {
+		Deploy.Log(run, model.LogLevelWarn, model.LogSourceKubea, notice)
+	}
+	return run, nil
+}
+
+// Reject 驳回等待审批的运行
+func (r *deployRun) Reject(run *model.DeployRun, approver, reason string) (*model.DeployRun, error) {
+	if err := r.approve(run, approver); err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("由 %s 驳回", approver)
+	if reason != "" {
+		message = fmt.Sprintf("%s: %s", message, reason)
+	}
+	return DeployRun.Transit(run.ID, model.DeployCancelled, message)
+}
+
+// Pending 等待审批的运行
+func (*deployRun) Pending() ([]*model.DeployRun, error) {
+	return dao.DeployRun.Pending()
+}
+
+// approve 记录审批人到运行记录
+func (*deployRun) approve(run *model.DeployRun, approver string) error {
+	if run.State != model.DeployPending {
+		return errors.New("该发布不在待审批状态")
+	}
+
+	now := time.Now()
+	run.Approver = approver
+	run.ApprovedAt = &now
+	return dao.DeployRun.Update(run.ID, &model.DeployRun{Approver: approver, ApprovedAt: &now})
+}
+
+// create 创建运行记录，等待审批的运行不校验锁及冻结窗口，也不同步到发布任务，避免覆盖进行中的运行
+// 进行中的运行写入 active_key，并发创建时由唯一索引拒绝
+func (r *deployRun) create(d *model.Deploy, builder string, state model.DeployState, emergency *Emergency) (*model.DeployRun, error) {
+	var notice string
+	if state != model.DeployPending {
+		var err error
+		if notice, err = guard(d.AppId, d.En, emergency); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	run := &model.DeployRun{
+		ID:       snowflake.GenID(),
+		DeployId: d.ID,
+		AppId:    d.AppId,
+		En:       d.En,
+		Branch:   d.Branch,
+		Tag:      d.Tag,
+		Builder:  builder,
+		State:    state,
+		QueuedAt: &now,
+	}
+	if state != model.DeployPending {
+		run.ActiveKey = model.DeployActiveKey(d.AppId, d.En)
+	}
+	if err := dao.DeployRun.Add(run); err != nil {
+		if errors.Is(err, dao.ErrDeployRunActive) {
+			return nil, locked(d.AppId, d.En)
+		}
+		return nil, err
+	}
+
+	if state != model.DeployPending {
+		if err := attach(d, run); err != nil {
+			return nil, err
+		}
+	}
+	if notice != "" {
+		Deploy.Log(run, model.LogLevelWarn, model.LogSourceKubea, notice)
+	}
+	return run, nil
+}
+
+// attach 将运行同步为发布任务的最近一次运行
+func attach(d *model.Deploy, run *model.DeployRun) error {
+	if d.StartTime == "" {
+		d.StartTime = time.Now().Format("2006-01-02 15:04:05")
+	}
+	d.Branch = run.Branch
+	d.Tag = run.Tag
+	d.Builder = run.Builder
+	d.State = run.State
+	d.RunId = run.ID
+	return dao.Deploy.Update(d)
+}
+
+// guard 校验应用在环境中没有进行中的运行，且环境不在冻结窗口
+// 只用于提前给出提示，并发时以 active_key 唯一索引为准
+func guard(appId uint, en string, emergency *Emergency) (string, error) {
+	_, has, err := dao.DeployRun.Active(appId, en)
+	if err != nil {
+		return "", err
+	}
+	if has {
+		return "", locked(appId, en)
+	}
+	return Freeze.Check(en, emergency)
+}
+
+// locked 应用在环境中已有进行中的运行
+func locked(appId uint, en string) error {
+	msg := fmt.Sprintf("该应用在 %s 环境已有进行中的发布，请等待完成或取消后重试", en)
+	if active, has, err := dao.DeployRun.Active(appId, en); err == nil && has {
+		msg = fmt.Sprintf("该应用在 %s 环境已有进行中的发布(run %d，%s 发起)，请等待完成或取消后重试", en, active.ID, active.Builder)
+	}
+	return &DeployBlockedError{Reason: BlockedLocked, Msg: msg}
+}
+
+// Transit 迁移运行状态，同步到发布任务并写入日志，重复的回调直接忽略
+func (*deployRun) Transit(id int64, to model.DeployState, message string) (*model.DeployRun, error) {
+	run, err := DeployRun.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.State == to {
+		return run, nil
+	}
+	if !canTransit(run.State, to) {
+		return nil, errors.New(fmt.Sprintf("发布状态不能从 %s 变为 %s", run.State, to))
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"state":                to,
+		deployStageColumns[to]: now,
+	}
+	if message != "" {
+		updates["message"] = message
+	}
+	// 审批通过后开始占用 应用+环境，结束后释放
+	if run.State == model.DeployPending && to == model.DeployQueued {
+		updates["active_key"] = model.DeployActiveKey(run.AppId, run.En)
+	}
+	if to.Finished() {
+		updates["active_key"] = nil
+	}
+	ok, err := dao.DeployRun.Transit(id, run.State, updates)
+	if errors.Is(err, dao.ErrDeployRunActive) {
+		return nil, locked(run.AppId, run.En)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("发布状态已变更，请刷新后重试")
+	}
+	run.State = to
+
+	// 只同步最近一次运行的状态
+	d, has, err := dao.Deploy.Get(run.DeployId)
+	if err != nil {
+		return nil, err
+	}
+	if has && d.RunId == run.ID {
+		d.State = to
+		if to.Finished() && run.QueuedAt != nil {
+			d.Duration = now.Sub(*run.QueuedAt).Truncate(time.Second).String()
+		}
+		if err := dao.Deploy.Update(d); err != nil {
+			return nil, err
+		}
+	}
+
+	content := deployStageLogs[to]
+	if message != "" {
+		content = fmt.Sprintf("%s %s", content, message)
+	}
+	level := model.LogLevelInfo
+	if to == model.DeployFailed {
+		level = model.LogLevelError
+	} else if to == model.DeployCancelled {
+		level = model.LogLevelWarn
+	}
+	Deploy.Log(run, level, model.LogSourceKubea, content)
+	return run, nil
+}
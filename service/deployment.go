@@ -59,22 +59,21 @@ func (d *deployment) fromCells(cells []DataCell) []appsv1.Deployment {
 }
 
 // GetDeployments 获取deployment列表
-func (d *deployment) GetDeployments(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (deploymentResp *DeploymentResp, err error) {
-	deploymentList, err := client.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Deployment 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Deployment 列表失败, %v\n", err))
+func (d *deployment) GetDeployments(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (deploymentResp *DeploymentResp, err error) {
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[appsv1.Deployment](K8s.cache(client), "deployments", namespace)
+	if !ok {
+		deploymentList, err := client.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Deployment 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Deployment 列表失败, %v\n", err))
+		}
+		items = deploymentList.Items
 	}
 	//实例化dataSelector对象
 	selectableData := &dataSelector{
-		GenericDataList: d.toCells(deploymentList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: d.toCells(items),
+		dataSelectQuery: query,
 	}
 
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
@@ -93,6 +92,9 @@ func (d *deployment) GetDeployments(client *kubernetes.Clientset, filterName, na
 
 // GetDeploymentDetail 获取 deployment 详情
 func (d *deployment) GetDeploymentDetail(client *kubernetes.Clientset, deploymentName, namespace string) (deployment *appsv1.Deployment, err error) {
+	if item, ok := cacheGet[appsv1.Deployment](K8s.cache(client), "deployments", namespace, deploymentName); ok {
+		return item, nil
+	}
 	deploymentDetail, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取Deployment详情失败, %v\n", err))
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"kubea/dao"
 	"kubea/model"
@@ -11,9 +12,130 @@ var Env env
 
 type env struct{}
 
-// List 返回环境列表
+// List 返回环境列表，包含审批角色
 func (*env) List(envName string, page, limit int) (*dao.Envs, error) {
-	return dao.Env.List(envName, page, limit)
+	data, err := dao.Env.List(envName, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range data.Items {
+		if item.Approvers, err = approvers(item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Chain 返回晋级链中的环境
+func (*env) Chain() ([]*model.Env, error) {
+	return dao.Env.GetChain()
+}
+
+// Policy 更新环境的晋级顺序、是否需要审批及审批角色
+func (*env) Policy(e *model.Env) error {
+	_, has, err := dao.Env.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("查询无此环境")
+	}
+
+	if e.Sort < 0 {
+		return errors.New("晋级顺序不能小于 0")
+	}
+	if e.Sort > 0 {
+		chain, err := dao.Env.GetChain()
+		if err != nil {
+			return err
+		}
+		for _, item := range chain {
+			if item.ID != e.ID && item.Sort == e.Sort {
+				return errors.New(fmt.Sprintf("晋级顺序 %d 已被环境 %s 使用", e.Sort, item.Name))
+			}
+		}
+	}
+
+	if err := dao.Env.UpdatePolicy(e); err != nil {
+		return err
+	}
+	return dao.Env.SetApprovers(e.ID, e.Approvers)
+}
+
+// Next 返回晋级链中的下一个环境
+func (*env) Next(en string) (*model.Env, error) {
+	chain, err := dao.Env.GetChain()
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range chain {
+		if item.Name != en {
+			continue
+		}
+		if i == len(chain)-1 {
+			return nil, errors.New(fmt.Sprintf("%s 已是晋级链中的最后一个环境", en))
+		}
+		return chain[i+1], nil
+	}
+	return nil, errors.New(fmt.Sprintf("环境 %s 不在晋级链中", en))
+}
+
+// RequirePromotion 是否为晋级链中第一个之后的环境，这些环境不能直接发布，只能晋级或经过审批
+func (*env) RequirePromotion(en string) (bool, error) {
+	chain, err := dao.Env.GetChain()
+	if err != nil {
+		return false, err
+	}
+	for i, item := range chain {
+		if item.Name == en {
+			return i > 0, nil
+		}
+	}
+	return false, nil
+}
+
+// RequireApproval 发布到该环境是否需要审批，未登记的环境不需要
+func (*env) RequireApproval(en string) (bool, error) {
+	data, has, err := dao.Env.Has(en)
+	if err != nil {
+		return false, err
+	}
+	return has && data.Approval, nil
+}
+
+// CanApprove 角色能否审批该环境的发布，超级管理员始终可以审批
+func (*env) CanApprove(roleID uint, en string) (bool, error) {
+	if roleID == 1 {
+		return true, nil
+	}
+
+	data, has, err := dao.Env.Has(en)
+	if err != nil || !has {
+		return false, err
+	}
+	roleIDs, err := approvers(data.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range roleIDs {
+		if id == roleID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// approvers 查询环境的审批角色 ID
+func approvers(envID uint) ([]uint, error) {
+	data, err := dao.Env.GetApprovers(envID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(data))
+	for _, item := range data {
+		ids = append(ids, item.RoleID)
+	}
+	return ids, nil
 }
 
 // Add 创建环境
@@ -54,5 +176,8 @@ func (*env) Delete(id uint) error {
 		return errors.New("当前环境关联URL信息，请先删除关联信息")
 	}
 
-	return dao.Env.Delete(id)
+	if err := dao.Env.Delete(id); err != nil {
+		return err
+	}
+	return dao.Env.SetApprovers(id, nil)
 }
@@ -8,7 +8,6 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"kubea/dao"
 	"kubea/model"
-	"time"
 )
 
 var Event event
@@ -16,18 +15,16 @@ var Event event
 type event struct{}
 
 // GetList 获取列表
-func (*event) GetList(name, cluster string, page, limit int) (*dao.Events, error) {
-	data, err := dao.Event.GetList(name, cluster, page, limit)
+func (*event) GetList(name, cluster string, namespaces []string, page, limit int) (*dao.Events, error) {
+	data, err := dao.Event.GetList(name, cluster, namespaces, page, limit)
 	if err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
-// WatchEventTask informer监听
-func (*event) WatchEventTask(cluster string) {
-	// 实例化informer
-	informerFactory := informers.NewSharedInformerFactory(K8s.ClientMap[cluster], time.Minute)
+// WatchEventTask informer监听，stopCh 关闭时退出
+func (*event) WatchEventTask(cluster string, informerFactory informers.SharedInformerFactory, stopCh <-chan struct{}) {
 	// 监听资源
 	informer := informerFactory.Core().V1().Events()
 	// 添加事件handler
@@ -40,14 +37,13 @@ func (*event) WatchEventTask(cluster string) {
 	)
 
 	// 处理启动和优雅关闭
-	stopCh := make(chan struct{})
-	defer close(stopCh)
 	informerFactory.Start(stopCh)
 	if !cache.WaitForCacheSync(stopCh, informer.Informer().HasSynced) {
-		zap.L().Error("同步cache超时")
+		zap.L().Error(fmt.Sprintf("集群 %s: 同步cache超时", cluster))
 		return
 	}
 	<-stopCh
+	zap.L().Info(fmt.Sprintf("集群 %s: 停止监听event", cluster))
 
 }
 
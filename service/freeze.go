@@ -0,0 +1,214 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"kubea/dao"
+	"kubea/model"
+	"kubea/utils"
+	"time"
+)
+
+var Freeze freeze
+
+type freeze struct{}
+
+// 发布被拒绝的原因
+const (
+	BlockedLocked = "locked"
+	BlockedFrozen = "frozen"
+	// BlockedApproval 需要审批的环境不能由 Jenkins 直接发起
+	BlockedApproval = "approval"
+	// BlockedPromotion 晋级链中靠后的环境只能通过晋级或审批发布
+	BlockedPromotion = "promotion"
+)
+
+// DeployBlockedError 发布被锁、处于冻结窗口、需要审批或只能晋级，接口据此返回 409，便于前端及 Jenkins 区分
+type DeployBlockedError struct {
+	Reason string `json:"reason"`
+	Msg    string `json:"msg"`
+	// 冻结窗口的结束时间，被锁时为空
+	Until *time.Time `json:"until,omitempty"`
+}
+
+func (e *DeployBlockedError) Error() string {
+	return e.Msg
+}
+
+// Emergency 紧急发布，冻结窗口允许的角色填写原因后可以在冻结期间发布
+type Emergency struct {
+	RoleID uint
+	Reason string
+}
+
+// List 查询冻结窗口，包含允许紧急发布的角色
+func (*freeze) List(en string) ([]*model.FreezeWindow, error) {
+	data, err := dao.Freeze.List(en)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range data {
+		if item.OverrideRoles, err = overrideRoles(item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Add 新增
+func (*freeze) Add(w *model.FreezeWindow) error {
+	if err := validateFreeze(w); err != nil {
+		return err
+	}
+	if err := dao.Freeze.Add(w); err != nil {
+		return err
+	}
+	return dao.Freeze.SetOverrides(w.ID, w.OverrideRoles)
+}
+
+// Update 更新
+func (*freeze) Update(w *model.FreezeWindow) error {
+	data, has, err := dao.Freeze.Get(w.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("查询无此冻结窗口")
+	}
+	if err := validateFreeze(w); err != nil {
+		return err
+	}
+
+	data.Name = w.Name
+	data.En = w.En
+	data.Type = w.Type
+	data.Cron = w.Cron
+	data.Duration = w.Duration
+	data.StartAt = w.StartAt
+	data.EndAt = w.EndAt
+	data.Enabled = w.Enabled
+	data.Description = w.Description
+	if err := dao.Freeze.Save(data); err != nil {
+		return err
+	}
+	return dao.Freeze.SetOverrides(data.ID, w.OverrideRoles)
+}
+
+// Delete 删除
+func (*freeze) Delete(id uint) error {
+	return dao.Freeze.Delete(id)
+}
+
+// Active 查询环境在 at 时刻生效的冻结窗口，返回窗口及结束时间
+func (*freeze) Active(en string, at time.Time) (*model.FreezeWindow, *time.Time, error) {
+	windows, err := dao.Freeze.GetEnabled(en)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, w := range windows {
+		switch w.Type {
+		case model.FreezeDate:
+			if w.StartAt != nil && w.EndAt != nil && !at.Before(*w.StartAt) && at.Before(*w.EndAt) {
+				return w, w.EndAt, nil
+			}
+		case model.FreezeCron:
+			cron, err := utils.ParseCron(w.Cron)
+			if err != nil {
+				continue
+			}
+			// 窗口开始时间在 (at - duration, at] 之间时处于冻结期
+			duration := time.Duration(w.Duration) * time.Minute
+			if start, ok := cron.Last(at.Add(-duration).Add(time.Minute), at); ok {
+				end := start.Add(duration)
+				return w, &end, nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// Check 校验环境当前能否发布，处于冻结窗口时只允许紧急发布，返回需要写入发布日志的紧急发布说明
+func (*freeze) Check(en string, emergency *Emergency) (string, error) {
+	w, until, err := Freeze.Active(en, time.Now())
+	if err != nil || w == nil {
+		return "", err
+	}
+
+	blocked := &DeployBlockedError{
+		Reason: BlockedFrozen,
+		Msg:    fmt.Sprintf("%s 环境处于发布冻结窗口 %s，%s 前禁止发布", en, w.Name, until.Format("2006-01-02 15:04")),
+		Until:  until,
+	}
+	if emergency == nil {
+		return "", blocked
+	}
+	if emergency.Reason == "" {
+		blocked.Msg += "，紧急发布需要填写原因"
+		return "", blocked
+	}
+
+	allowed := emergency.RoleID == 1
+	if !allowed {
+		roleIDs, err := overrideRoles(w.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range roleIDs {
+			if id == emergency.RoleID {
+				allowed = true
+				break
+			}
+		}
+	}
+	if !allowed {
+		blocked.Msg += "，当前角色无权紧急发布"
+		return "", blocked
+	}
+	return fmt.Sprintf("冻结窗口 %s 期间紧急发布，原因: %s", w.Name, emergency.Reason), nil
+}
+
+// overrideRoles 查询窗口允许紧急发布的角色 ID
+func overrideRoles(windowID uint) ([]uint, error) {
+	data, err := dao.Freeze.GetOverrides(windowID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(data))
+	for _, item := range data {
+		ids = append(ids, item.RoleID)
+	}
+	return ids, nil
+}
+
+// validateFreeze 校验环境、窗口类型及时间
+func validateFreeze(w *model.FreezeWindow) error {
+	if w.Name == "" {
+		return errors.New("冻结窗口名称不能为空")
+	}
+	if w.En != "*" {
+		if _, has, err := dao.Env.Has(w.En); err != nil {
+			return err
+		} else if !has {
+			return errors.New(fmt.Sprintf("环境 %s 不存在", w.En))
+		}
+	}
+
+	switch w.Type {
+	case model.FreezeCron:
+		if _, err := utils.ParseCron(w.Cron); err != nil {
+			return err
+		}
+		// 最长一周，超过时请使用固定时间段
+		if w.Duration <= 0 || w.Duration > 7*24*60 {
+			return errors.New("持续时间需在 1 到 10080 分钟之间")
+		}
+	case model.FreezeDate:
+		if w.StartAt == nil || w.EndAt == nil || !w.EndAt.After(*w.StartAt) {
+			return errors.New("结束时间需晚于开始时间")
+		}
+	default:
+		return errors.New(fmt.Sprintf("不支持的冻结窗口类型 %s，只支持 cron、date", w.Type))
+	}
+	return nil
+}
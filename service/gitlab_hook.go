@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"strings"
+)
+
+// gitlabZeroSha 删除分支、tag 时 after 为全 0
+const gitlabZeroSha = "0000000000000000000000000000000000000000"
+
+// GitlabHook GitLab push、tag push、merge request 事件中用到的字段
+type GitlabHook struct {
+	ObjectKind   string `json:"object_kind"`
+	Ref          string `json:"ref"`
+	After        string `json:"after"`
+	UserUsername string `json:"user_username"`
+	User         struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		Action       string `json:"action"`
+		TargetBranch string `json:"target_branch"`
+	} `json:"object_attributes"`
+}
+
+// Hook 处理 GitLab webhook，按仓库匹配应用，再按规则自动发布，返回匹配的规则
+// 仓库 group/project 对应应用的 RepoName/AppName，merge request 只在合并时按目标分支发布
+// 触发 Jenkins 可能超过 GitLab 的 10s 超时，匹配后异步发布，结果写入日志
+func (*gitlab) Hook(body []byte) ([]*model.DeployRule, error) {
+	hook := new(GitlabHook)
+	if err := json.Unmarshal(body, hook); err != nil {
+		return nil, errors.New(fmt.Sprintf("GitLab 事件解析失败, %v", err))
+	}
+
+	var event, ref, username string
+	switch hook.ObjectKind {
+	case "push":
+		event, ref, username = model.GitlabEventPush, strings.TrimPrefix(hook.Ref, "refs/heads/"), hook.UserUsername
+	case "tag_push":
+		event, ref, username = model.GitlabEventTag, strings.TrimPrefix(hook.Ref, "refs/tags/"), hook.UserUsername
+	case "merge_request":
+		if hook.ObjectAttributes.Action != "merge" {
+			return nil, nil
+		}
+		event, ref, username = model.GitlabEventMergeRequest, hook.ObjectAttributes.TargetBranch, hook.User.Username
+	default:
+		return nil, nil
+	}
+	// 删除分支、tag 不触发发布
+	if hook.After == gitlabZeroSha {
+		return nil, nil
+	}
+
+	i := strings.LastIndex(hook.Project.PathWithNamespace, "/")
+	if i < 0 {
+		return nil, errors.New(fmt.Sprintf("GitLab 仓库路径格式错误: %s", hook.Project.PathWithNamespace))
+	}
+	app, has, err := dao.App.Has(hook.Project.PathWithNamespace[:i], hook.Project.PathWithNamespace[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+
+	rules, err := DeployRule.Match(app.ID, event, ref)
+	if err != nil {
+		return nil, err
+	}
+	go triggerRules(rules, event, ref, "gitlab:"+username)
+	return rules, nil
+}
+
+// triggerRules 按匹配的规则依次发布
+func triggerRules(rules []*model.DeployRule, event, ref, builder string) {
+	for _, rule := range rules {
+		run, err := Deploy.Trigger(rule, ref, builder)
+		if err != nil {
+			// 一个环境发布失败不影响其他规则
+			zap.L().Error(fmt.Sprintf("GitLab %s %s 自动发布 %s 失败, %v", event, ref, rule.En, err))
+			continue
+		}
+		zap.L().Info(fmt.Sprintf("GitLab %s %s 自动发布 %s, run %d, 状态 %s", event, ref, rule.En, run.ID, run.State))
+	}
+}
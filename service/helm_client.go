@@ -5,7 +5,13 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/action"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"log"
 	"os"
 )
@@ -16,22 +22,66 @@ type helmConfig struct{}
 
 // GetAction 获取 helm action 配置
 func (*helmConfig) GetAction(cluster, namespace string) (*action.Configuration, error) {
-	// 获取 kubeconfig
-	kubeconfig, ok := K8s.KubeConfMap[cluster]
-	if !ok {
+	// 获取集群配置
+	conf, err := K8s.GetConfig(cluster)
+	if err != nil {
+		zap.L().Error("actionConfig初始化失败,cluster不存在")
+		return nil, errors.New("actionConfig初始化失败,cluster不存在")
+	}
+	// 与 GetClient 一样，不访问不可用的集群
+	if err := K8s.CheckHealth(cluster); err != nil {
+		return nil, err
+	}
+	apiConf, err := K8s.GetApiConfig(cluster)
+	if err != nil {
 		zap.L().Error("actionConfig初始化失败,cluster不存在")
 		return nil, errors.New("actionConfig初始化失败,cluster不存在")
 	}
 
 	// new 一个 actionConfig 对象
 	actionConfig := new(action.Configuration)
-	cf := &genericclioptions.ConfigFlags{
-		KubeConfig:  &kubeconfig,
-		ClusterName: &namespace,
+	getter := &restClientGetter{
+		namespace: namespace,
+		config:    conf,
+		apiConfig: apiConf,
 	}
-	if err := actionConfig.Init(cf, namespace, os.Getenv("HELM_DRIVER"), log.Printf); err != nil {
+	if err := actionConfig.Init(getter, namespace, os.Getenv("HELM_DRIVER"), log.Printf); err != nil {
 		zap.L().Error(fmt.Sprintf("actionConfig初始化失败, %v\n", err))
 		return nil, errors.New(fmt.Sprintf("actionConfig初始化失败, %v\n", err))
 	}
 	return actionConfig, nil
 }
+
+// restClientGetter 使用数据库中的集群配置，实现 genericclioptions.RESTClientGetter
+type restClientGetter struct {
+	namespace string
+	config    *rest.Config
+	apiConfig *clientcmdapi.Config
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(dc), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	dc, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return restmapper.NewShortcutExpander(mapper, dc), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientcmd.NewDefaultClientConfig(*g.apiConfig, &clientcmd.ConfigOverrides{
+		Context: clientcmdapi.Context{Namespace: g.namespace},
+	})
+}
@@ -0,0 +1,478 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	"kubea/dao"
+	"kubea/model"
+	"kubea/settings"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HelmRepoHttp = "http"
+	HelmRepoOci  = "oci"
+)
+
+var HelmRepo helmRepo
+
+type helmRepo struct {
+	mu sync.RWMutex
+	// 远程仓库的 index，按仓库名称查找，由 RefreshTask 定时更新
+	indexes map[string]*repo.IndexFile
+}
+
+// ChartSearchResult chart 搜索结果
+type ChartSearchResult struct {
+	Repo        string `json:"repo"`
+	Name        string `json:"name"`
+	Chart       string `json:"chart"` // 安装时使用的 chart 引用，如 bitnami/nginx
+	Version     string `json:"version"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+// List 返回仓库列表，不返回密码
+func (*helmRepo) List() ([]*model.HelmRepo, error) {
+	repos, err := dao.HelmRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range repos {
+		item.Password = ""
+	}
+	return repos, nil
+}
+
+// Add 新增仓库，并刷新 index
+func (h *helmRepo) Add(r *model.HelmRepo) error {
+	if err := validateHelmRepo(r); err != nil {
+		return err
+	}
+	_, has, err := dao.HelmRepo.Has(r.Name)
+	if err != nil {
+		return err
+	}
+	if has {
+		return errors.New("该仓库已存在，请重新添加")
+	}
+
+	if err := dao.HelmRepo.Add(r); err != nil {
+		return err
+	}
+	// 刷新失败只记录错误，仓库仍然保留
+	_ = h.refresh(r)
+	return nil
+}
+
+// Update 更新仓库，并刷新 index，密码为空时保留原值
+func (h *helmRepo) Update(r *model.HelmRepo) error {
+	old, has, err := dao.HelmRepo.Get(r.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该仓库不存在")
+	}
+	if r.Name != "" && r.Name != old.Name {
+		return errors.New("仓库名称不允许修改")
+	}
+	r.Name = old.Name
+	if r.Url == "" {
+		r.Url = old.Url
+	}
+	if r.Type == "" {
+		r.Type = old.Type
+	}
+	if err := validateHelmRepo(r); err != nil {
+		return err
+	}
+
+	if err := dao.HelmRepo.Update(r); err != nil {
+		return err
+	}
+	data, _, err := dao.HelmRepo.Get(r.ID)
+	if err != nil {
+		return err
+	}
+	_ = h.refresh(data)
+	return nil
+}
+
+// Delete 删除仓库，并清理 index 缓存
+func (h *helmRepo) Delete(id uint) error {
+	data, has, err := dao.HelmRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该仓库不存在")
+	}
+
+	if err := dao.HelmRepo.Delete(id); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	delete(h.indexes, data.Name)
+	h.mu.Unlock()
+	_ = os.Remove(filepath.Join(helmRepoCache(), helmpath.CacheIndexFile(data.Name)))
+	_ = os.Remove(filepath.Join(helmRepoCache(), helmpath.CacheChartsFile(data.Name)))
+	return nil
+}
+
+// Refresh 手动刷新仓库 index
+func (h *helmRepo) Refresh(id uint) error {
+	data, has, err := dao.HelmRepo.Get(id)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该仓库不存在")
+	}
+	return h.refresh(data)
+}
+
+// RefreshTask 定时刷新所有仓库的 index
+func (h *helmRepo) RefreshTask(interval int) {
+	if interval <= 0 {
+		interval = 1800
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		repos, err := dao.HelmRepo.GetAll()
+		if err == nil {
+			for _, item := range repos {
+				_ = h.refresh(item)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// refresh 下载 http 仓库的 index.yaml，oci 仓库没有 index，只校验登录
+func (h *helmRepo) refresh(r *model.HelmRepo) error {
+	var err error
+	if r.Type == HelmRepoOci {
+		_, err = ociClient(r)
+	} else {
+		var index *repo.IndexFile
+		index, err = downloadIndex(r)
+		if err == nil {
+			h.mu.Lock()
+			if h.indexes == nil {
+				h.indexes = make(map[string]*repo.IndexFile)
+			}
+			h.indexes[r.Name] = index
+			h.mu.Unlock()
+		}
+	}
+
+	refreshError := ""
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("刷新仓库 %s 失败, %v", r.Name, err))
+		refreshError = err.Error()
+	}
+	_ = dao.HelmRepo.UpdateRefresh(r.ID, time.Now(), refreshError)
+	if err != nil {
+		return errors.New(fmt.Sprintf("刷新仓库 %s 失败, %v", r.Name, err))
+	}
+	return nil
+}
+
+// index 获取仓库的 index，内存中没有时从缓存目录加载
+func (h *helmRepo) index(name string) (*repo.IndexFile, error) {
+	h.mu.RLock()
+	index, ok := h.indexes[name]
+	h.mu.RUnlock()
+	if ok {
+		return index, nil
+	}
+
+	index, err := repo.LoadIndexFile(filepath.Join(helmRepoCache(), helmpath.CacheIndexFile(name)))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("仓库 %s 的 index 不存在，请先刷新仓库", name))
+	}
+	h.mu.Lock()
+	if h.indexes == nil {
+		h.indexes = make(map[string]*repo.IndexFile)
+	}
+	h.indexes[name] = index
+	h.mu.Unlock()
+	return index, nil
+}
+
+// Search 在 http 仓库中搜索 chart，keyword 匹配名称、描述和关键字，versions 为 true 时返回所有版本
+func (h *helmRepo) Search(keyword, repoName string, versions bool) ([]*ChartSearchResult, error) {
+	repos, err := dao.HelmRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	keyword = strings.ToLower(keyword)
+	data := make([]*ChartSearchResult, 0)
+	for _, r := range repos {
+		if r.Type == HelmRepoOci || (repoName != "" && r.Name != repoName) {
+			continue
+		}
+		index, err := h.index(r.Name)
+		if err != nil {
+			continue
+		}
+		for name, chartVersions := range index.Entries {
+			if len(chartVersions) == 0 || !matchChart(name, chartVersions[0], keyword) {
+				continue
+			}
+			if !versions {
+				chartVersions = chartVersions[:1]
+			}
+			for _, cv := range chartVersions {
+				data = append(data, &ChartSearchResult{
+					Repo:        r.Name,
+					Name:        name,
+					Chart:       r.Name + "/" + name,
+					Version:     cv.Version,
+					AppVersion:  cv.AppVersion,
+					Description: cv.Description,
+					Icon:        cv.Icon,
+				})
+			}
+		}
+	}
+
+	// 按 chart 名称排序，同一 chart 的版本保持 index 中的降序
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].Chart < data[j].Chart
+	})
+	return data, nil
+}
+
+// IsRepoChart 判断 chart 引用是否来自远程仓库，如 oci://harbor/project/nginx 或 bitnami/nginx
+func (*helmRepo) IsRepoChart(chart string) bool {
+	if strings.HasPrefix(chart, "oci://") {
+		return true
+	}
+	repoName, name, ok := strings.Cut(chart, "/")
+	if !ok || repoName == "" || name == "" || strings.ContainsAny(name, "/:") {
+		return false
+	}
+	_, has, err := dao.HelmRepo.Has(repoName)
+	return err == nil && has
+}
+
+// LoadChart 从远程仓库下载并加载 chart，version 为空时使用最新版本
+func (h *helmRepo) LoadChart(chart, version string) (*helmchart.Chart, error) {
+	if strings.HasPrefix(chart, "oci://") {
+		return pullOciChart(ociRepo(chart), strings.TrimPrefix(chart, "oci://"), version)
+	}
+
+	repoName, name, _ := strings.Cut(chart, "/")
+	r, has, err := dao.HelmRepo.Has(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New(fmt.Sprintf("仓库 %s 不存在", repoName))
+	}
+	if r.Type == HelmRepoOci {
+		ref := strings.TrimPrefix(strings.TrimSuffix(r.Url, "/"), "oci://") + "/" + name
+		return pullOciChart(r, ref, version)
+	}
+	return h.pullHttpChart(r, name, version)
+}
+
+// pullHttpChart 根据 index 找到 chart 地址并下载
+func (h *helmRepo) pullHttpChart(r *model.HelmRepo, name, version string) (*helmchart.Chart, error) {
+	index, err := h.index(r.Name)
+	if err != nil {
+		return nil, err
+	}
+	cv, err := index.Get(name, version)
+	if err != nil || len(cv.URLs) == 0 {
+		return nil, errors.New(fmt.Sprintf("仓库 %s 中不存在 chart %s %s", r.Name, name, version))
+	}
+	chartUrl, err := repo.ResolveReferenceURL(r.Url, cv.URLs[0])
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("解析chart地址失败, %v", err))
+	}
+
+	u, err := url.Parse(chartUrl)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("解析chart地址失败, %v", err))
+	}
+	g, err := getter.All(cli.New()).ByScheme(u.Scheme)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("不支持的chart地址 %s", chartUrl))
+	}
+	buf, err := g.Get(chartUrl,
+		getter.WithURL(r.Url),
+		getter.WithBasicAuth(r.Username, r.Password),
+		getter.WithInsecureSkipVerifyTLS(r.Insecure),
+	)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("下载chart %s 失败, %v", chartUrl, err))
+		return nil, errors.New(fmt.Sprintf("下载chart %s 失败, %v", chartUrl, err))
+	}
+
+	chartRequested, err := loader.LoadArchive(buf)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+	}
+	return chartRequested, nil
+}
+
+// pullOciChart 从 oci 仓库拉取 chart，ref 不带 oci:// 前缀，r 为空时匿名拉取
+func pullOciChart(r *model.HelmRepo, ref, version string) (*helmchart.Chart, error) {
+	client, err := ociClient(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		tags, err := client.Tags(ref)
+		if err != nil || len(tags) == 0 {
+			return nil, errors.New(fmt.Sprintf("获取 %s 的版本失败, %v", ref, err))
+		}
+		version = tags[0]
+	}
+	result, err := client.Pull(ref+":"+version, registry.PullOptWithChart(true))
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("拉取chart %s:%s 失败, %v", ref, version, err))
+		return nil, errors.New(fmt.Sprintf("拉取chart %s:%s 失败, %v", ref, version, err))
+	}
+
+	chartRequested, err := loader.LoadArchive(bytes.NewReader(result.Chart.Data))
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+	}
+	return chartRequested, nil
+}
+
+// ociRepo 根据地址前缀查找 oci 仓库，用于获取凭证
+func ociRepo(chart string) *model.HelmRepo {
+	repos, err := dao.HelmRepo.GetAll()
+	if err != nil {
+		return nil
+	}
+	for _, r := range repos {
+		if r.Type == HelmRepoOci && strings.HasPrefix(chart, strings.TrimSuffix(r.Url, "/")+"/") {
+			return r
+		}
+	}
+	return nil
+}
+
+// ociClient 创建 registry client，仓库配置了用户名时先登录
+func ociClient(r *model.HelmRepo) (*registry.Client, error) {
+	client, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(filepath.Join(helmRepoCache(), "registry", "config.json")),
+	)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("创建 registry client 失败, %v", err))
+	}
+	if r == nil || r.Username == "" {
+		return client, nil
+	}
+
+	host, _, _ := strings.Cut(strings.TrimPrefix(r.Url, "oci://"), "/")
+	if err := client.Login(host,
+		registry.LoginOptBasicAuth(r.Username, r.Password),
+		registry.LoginOptInsecure(r.Insecure),
+	); err != nil {
+		return nil, errors.New(fmt.Sprintf("登录 %s 失败, %v", host, err))
+	}
+	return client, nil
+}
+
+// downloadIndex 下载 http 仓库的 index.yaml 到缓存目录并加载
+func downloadIndex(r *model.HelmRepo) (*repo.IndexFile, error) {
+	chartRepo, err := repo.NewChartRepository(&repo.Entry{
+		Name:                  r.Name,
+		URL:                   r.Url,
+		Username:              r.Username,
+		Password:              r.Password,
+		InsecureSkipTLSverify: r.Insecure,
+	}, getter.All(cli.New()))
+	if err != nil {
+		return nil, err
+	}
+	chartRepo.CachePath = helmRepoCache()
+
+	path, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return nil, err
+	}
+	return repo.LoadIndexFile(path)
+}
+
+// validateHelmRepo 校验仓库名称、类型和地址
+func validateHelmRepo(r *model.HelmRepo) error {
+	if r.Name == "" || strings.ContainsAny(r.Name, "/: ") {
+		return errors.New("仓库名称不能为空，且不能包含 / : 和空格")
+	}
+	if r.Type == "" {
+		r.Type = HelmRepoHttp
+		if strings.HasPrefix(r.Url, "oci://") {
+			r.Type = HelmRepoOci
+		}
+	}
+
+	u, err := url.Parse(r.Url)
+	if err != nil || u.Host == "" {
+		return errors.New("仓库地址格式错误")
+	}
+	switch r.Type {
+	case HelmRepoHttp:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.New("http 仓库地址必须以 http:// 或 https:// 开头")
+		}
+	case HelmRepoOci:
+		if u.Scheme != "oci" {
+			return errors.New("oci 仓库地址必须以 oci:// 开头")
+		}
+	default:
+		return errors.New("仓库类型只能为 http 或 oci")
+	}
+	return nil
+}
+
+// helmRepoCache 仓库 index 缓存目录
+func helmRepoCache() string {
+	if settings.Conf.HelmRepoCache == "" {
+		return "./cache/helm"
+	}
+	return settings.Conf.HelmRepoCache
+}
+
+// matchChart 判断 chart 是否匹配搜索关键字，keyword 为空时全部匹配
+func matchChart(name string, cv *repo.ChartVersion, keyword string) bool {
+	if keyword == "" || strings.Contains(strings.ToLower(name), keyword) ||
+		strings.Contains(strings.ToLower(cv.Description), keyword) {
+		return true
+	}
+	for _, item := range cv.Keywords {
+		if strings.Contains(strings.ToLower(item), keyword) {
+			return true
+		}
+	}
+	return false
+}
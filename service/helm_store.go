@@ -1,18 +1,24 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
 	"io"
 	"kubea/dao"
 	"kubea/model"
 	"kubea/settings"
-	"mime/multipart"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -32,6 +38,13 @@ type releaseElement struct {
 	ChartVersion string `json:"chart_version"`
 	AppVersion   string `json:"app_version"`
 	Notes        string `json:"notes,omitempty"`
+	Manifest     string `json:"manifest,omitempty"`
+}
+
+// chartValues chart默认的values及其JSON Schema
+type chartValues struct {
+	Values string `json:"values"`
+	Schema string `json:"schema"`
 }
 
 type releaseElements struct {
@@ -39,31 +52,89 @@ type releaseElements struct {
 	Total int               `json:"total"`
 }
 
-// ListReleases release列表
-// 这里没有使用page和limit,这里的分页是前端实现的,翻页不发起请求
-// k8s资源使用了page和limit获取列表，每次翻页都发起请求
-func (*helmStore) ListReleases(actionConfig *action.Configuration, filterName string) (*releaseElements, error) {
+// ReleaseQuery release列表的过滤、排序、分页参数
+type ReleaseQuery struct {
+	FilterName string `form:"filter_name"`
+	// 状态过滤，逗号分隔，如 deployed,failed,pending-install，默认不显示 superseded 和 uninstalled
+	// helm 只在单独查询 superseded 时返回历史版本，与其他状态(包括 all)同时指定时会被过滤掉，因此只能单独查询
+	States string `form:"states"`
+	SortBy string `form:"sort_by"` // name 或 date，默认 name
+	Order  string `form:"order"`   // asc 或 desc，默认 asc
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// 默认显示的release状态
+const defaultReleaseStates = action.ListAll &^ action.ListSuperseded &^ action.ListUninstalled
+
+// ListReleases release列表，在服务端过滤、排序和分页
+// actionConfig 的 namespace 为空时，列出集群所有名称空间的release
+// namespaces 为 nil 时不限制名称空间
+func (*helmStore) ListReleases(actionConfig *action.Configuration, query *ReleaseQuery, namespaces []string) (*releaseElements, error) {
+	stateMask, err := releaseStates(query.States)
+	if err != nil {
+		return nil, err
+	}
+	if query.SortBy != "" && query.SortBy != "name" && query.SortBy != "date" {
+		return nil, errors.New("排序字段只能为 name 或 date")
+	}
+	if query.Order != "" && query.Order != "asc" && query.Order != "desc" {
+		return nil, errors.New("排序方式只能为 asc 或 desc")
+	}
+
 	// new一个列表的client
 	client := action.NewList(actionConfig)
-	client.Filter = filterName
-
-	// 显示所有数据
+	client.Filter = query.FilterName
+	client.StateMask = stateMask
+	// 获取全部数据，过滤名称空间权限后再分页，保证总数正确
 	client.All = true
-	//client.Limit = limit
-	//client.Offset = offset
 	client.TimeFormat = "2006-01-02 15:04:05"
-
-	// 只获取已部署 helm
-	client.Deployed = true
 	results, err := client.Run()
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取Release列表失败, %v\n", err))
 		return nil, errors.New(fmt.Sprintf("获取Release列表失败, %v\n", err))
 	}
 
-	total := len(results)
-	elements := make([]*releaseElement, 0)
+	allowed := make(map[string]bool)
+	for _, ns := range namespaces {
+		allowed[ns] = true
+	}
+	filtered := make([]*release.Release, 0, len(results))
 	for _, r := range results {
+		if namespaces != nil && !allowed[r.Namespace] {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	total := len(filtered)
+
+	// 排序
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		if query.Order == "desc" {
+			a, b = b, a
+		}
+		if query.SortBy == "date" {
+			return a.Info.LastDeployed.Before(b.Info.LastDeployed)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	})
+
+	// 分页
+	start := query.Offset
+	if start < 0 || start > total {
+		start = total
+	}
+	end := total
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+
+	elements := make([]*releaseElement, 0, end-start)
+	for _, r := range filtered[start:end] {
 		elements = append(elements, constructReleaseElement(r, false))
 	}
 
@@ -73,6 +144,40 @@ func (*helmStore) ListReleases(actionConfig *action.Configuration, filterName st
 	}, nil
 }
 
+// releaseStates 将逗号分隔的状态转换为 helm 的状态掩码，pending 表示所有进行中的状态
+func releaseStates(states string) (action.ListStates, error) {
+	if states == "" {
+		return defaultReleaseStates, nil
+	}
+
+	var mask action.ListStates
+	for _, state := range strings.Split(states, ",") {
+		state = strings.TrimSpace(state)
+		switch state {
+		case "":
+			continue
+		case "all":
+			// 与其他状态同时查询时 helm 不会返回 superseded
+			mask |= action.ListAll &^ action.ListSuperseded
+		case "pending":
+			mask |= action.ListPendingInstall | action.ListPendingUpgrade | action.ListPendingRollback
+		default:
+			value := mask.FromName(state)
+			if value == action.ListUnknown {
+				return 0, errors.New("不支持的Release状态: " + state)
+			}
+			mask |= value
+		}
+	}
+	if mask == 0 {
+		return defaultReleaseStates, nil
+	}
+	if mask&action.ListSuperseded != 0 && mask != action.ListSuperseded {
+		return 0, errors.New("superseded 只能单独查询，不能与其他状态同时指定")
+	}
+	return mask, nil
+}
+
 // DetailRelease 获取release详情
 func (*helmStore) DetailRelease(actionConfig *action.Configuration, release string) (*release.Release, error) {
 	client := action.NewGet(actionConfig)
@@ -86,33 +191,171 @@ func (*helmStore) DetailRelease(actionConfig *action.Configuration, release stri
 
 // InstallRelease 安装Release
 // release release的名字
-// chart chart文件所在的路径
-func (*helmStore) InstallRelease(actionConfig *action.Configuration, release, chart, namespace string) error {
+// chart chart文件所在的路径，内置仓库中的chart名称，或远程仓库的chart引用，version 为chart版本
+// values 为用户提供的 values YAML，set 为 --set 格式的覆盖值，优先级高于 values
+// dryRun 为 true 时只渲染模板，不访问集群，返回渲染后的 manifest 和 NOTES
+func (*helmStore) InstallRelease(actionConfig *action.Configuration, release, chart, version, namespace, values string, set []string, dryRun bool) (*releaseElement, error) {
+	vals, err := mergeValues(values, set)
+	if err != nil {
+		return nil, err
+	}
+
 	client := action.NewInstall(actionConfig)
 	client.ReleaseName = release
 	//这里的namespace没啥用，主要安装在哪个namespace还是要看actionConfig初始化的namespace
 	client.Namespace = namespace
-	splitChart := strings.Split(chart, ".")
-	if splitChart[len(splitChart)-1] == "tgz" && !strings.Contains(chart, ":") {
-		chart = settings.Conf.UploadPath + chart
-	}
+	client.DryRun = dryRun
+	client.ClientOnly = dryRun
 
 	//加载chart文件，并给予文件内容生成k8s资源
-	chartRequested, err := loader.Load(chart)
+	chartRequested, err := loadChart(chart, version)
 	if err != nil {
-		zap.L().Error(fmt.Sprintf("加载Chart文件失败, %v\n", err))
-		return errors.New(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+		return nil, err
 	}
-	vals := make(map[string]interface{}, 0)
-	_, err = client.Run(chartRequested, vals)
+	data, err := client.Run(chartRequested, vals)
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("安装Release失败, %v\n", err))
-		return errors.New(fmt.Sprintf("安装Release失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("安装Release失败, %v\n", err))
+	}
+
+	element := constructReleaseElement(data, true)
+	if dryRun {
+		element.Manifest = data.Manifest
+	}
+	return element, nil
+}
+
+// ChartValues 返回chart默认的 values.yaml 和 values.schema.json，供用户编辑values时参考
+func (*helmStore) ChartValues(chart, version string) (*chartValues, error) {
+	chartRequested, err := loadChart(chart, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &chartValues{Schema: string(chartRequested.Schema)}
+	for _, file := range chartRequested.Raw {
+		if file.Name == chartutil.ValuesfileName {
+			data.Values = string(file.Data)
+			break
+		}
+	}
+	return data, nil
+}
+
+// UpgradeRelease 升级Release
+// chart chart文件所在的路径，values 和 set 同 InstallRelease
+// reuseValues 为 true 时在上一版本的 values 基础上合并
+func (*helmStore) UpgradeRelease(actionConfig *action.Configuration, release, chart, version, namespace, values string, set []string, reuseValues bool) (*releaseElement, error) {
+	vals, err := mergeValues(values, set)
+	if err != nil {
+		return nil, err
+	}
+
+	chartRequested, err := loadChart(chart, version)
+	if err != nil {
+		return nil, err
+	}
+
+	client := action.NewUpgrade(actionConfig)
+	client.Namespace = namespace
+	client.ReuseValues = reuseValues
+	data, err := client.Run(release, chartRequested, vals)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("升级Release失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("升级Release失败, %v\n", err))
+	}
+	return constructReleaseElement(data, true), nil
+}
+
+// historyElement release历史版本
+type historyElement struct {
+	Revision     int    `json:"revision"`
+	Updated      string `json:"updated"`
+	Status       string `json:"status"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chart_version"`
+	AppVersion   string `json:"app_version"`
+	Description  string `json:"description"`
+}
+
+// HistoryRelease 获取release历史版本，按版本倒序
+func (*helmStore) HistoryRelease(actionConfig *action.Configuration, release string, max int) ([]*historyElement, error) {
+	client := action.NewHistory(actionConfig)
+	client.Max = max
+	if client.Max <= 0 {
+		client.Max = 256
+	}
+	results, err := client.Run(release)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("获取Release历史版本失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("获取Release历史版本失败, %v\n", err))
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Version > results[j].Version
+	})
+	elements := make([]*historyElement, 0, len(results))
+	for _, r := range results {
+		element := &historyElement{
+			Revision:    r.Version,
+			Updated:     "-",
+			Status:      r.Info.Status.String(),
+			Description: r.Info.Description,
+		}
+		if r.Chart != nil && r.Chart.Metadata != nil {
+			element.Chart = r.Chart.Metadata.Name
+			element.ChartVersion = r.Chart.Metadata.Version
+			element.AppVersion = r.Chart.Metadata.AppVersion
+		}
+		if !r.Info.LastDeployed.IsZero() {
+			element.Updated = r.Info.LastDeployed.Format("2006-01-02 15:04:05")
+		}
+		elements = append(elements, element)
 	}
+	return elements, nil
+}
 
+// RollbackRelease 回滚release到指定版本，revision 为 0 时回滚到上一个版本
+func (*helmStore) RollbackRelease(actionConfig *action.Configuration, release string, revision int) error {
+	client := action.NewRollback(actionConfig)
+	client.Version = revision
+	if err := client.Run(release); err != nil {
+		zap.L().Error(fmt.Sprintf("回滚Release失败, %v\n", err))
+		return errors.New(fmt.Sprintf("回滚Release失败, %v\n", err))
+	}
 	return nil
 }
 
+// DiffReleaseValues 对比release两个版本的values，to 为 0 时与当前版本对比
+// all 为 true 时对比合并chart默认值后的完整values，否则只对比用户提供的values
+func (*helmStore) DiffReleaseValues(actionConfig *action.Configuration, release string, from, to int, all bool) ([]*ValueDiff, error) {
+	if from <= 0 {
+		return nil, errors.New("请指定要对比的版本")
+	}
+
+	getValues := func(revision int) (map[string]interface{}, error) {
+		client := action.NewGetValues(actionConfig)
+		client.Version = revision
+		client.AllValues = all
+		vals, err := client.Run(release)
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取Release版本 %d 的values失败, %v\n", revision, err))
+			return nil, errors.New(fmt.Sprintf("获取Release版本 %d 的values失败, %v\n", revision, err))
+		}
+		return vals, nil
+	}
+
+	fromValues, err := getValues(from)
+	if err != nil {
+		return nil, err
+	}
+	toValues, err := getValues(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues(fromValues, toValues), nil
+}
+
 // UninstallRelease 卸载release
 func (*helmStore) UninstallRelease(actionConfig *action.Configuration, release string) error {
 	client := action.NewUninstall(actionConfig)
@@ -124,35 +367,111 @@ func (*helmStore) UninstallRelease(actionConfig *action.Configuration, release s
 	return nil
 }
 
-// UploadChartFile chart文件上传
-// *multipart.FileHeader 获取上传文件header中的相关信息
-// multipart.File封装了读文件的操作
-func (*helmStore) UploadChartFile(file multipart.File, header *multipart.FileHeader) error {
-	filename := header.Filename
-	t := strings.Split(filename, ".")
-	if t[len(t)-1] != "tgz" {
-		zap.L().Error("chart文件必须以.tgz结尾")
-		return errors.New("chart文件必须以.tgz结尾")
+// ChartRejectedError chart文件校验未通过，接口据此返回 400，超过大小上限时返回 413
+type ChartRejectedError struct {
+	TooLarge bool
+	Msg      string
+}
+
+func (e *ChartRejectedError) Error() string {
+	return e.Msg
+}
+
+// chartRejected 并发上传同一版本时，唯一索引冲突同样按校验未通过处理
+func chartRejected(err error) error {
+	if errors.Is(err, dao.ErrChartExists) {
+		return &ChartRejectedError{Msg: err.Error()}
 	}
-	filePath := settings.Conf.UploadPath + filename
-	_, err := os.Stat(filePath)
-	if os.IsExist(err) {
-		zap.L().Error("chart文件已存在")
-		return errors.New("chart文件已存在")
+	return err
+}
+
+// UploadChartFile chart文件上传，file 为 multipart 中的文件内容，边读边写
+// 文件先流式写入临时文件并计算 sha256，校验、lint 通过后以 <sha256>.tgz 保存，并新增chart记录
+func (*helmStore) UploadChartFile(name string, file io.Reader) (*model.Chart, error) {
+	if !strings.HasSuffix(name, ".tgz") {
+		zap.L().Error("chart文件必须以.tgz结尾")
+		return nil, &ChartRejectedError{Msg: "chart文件必须以.tgz结尾"}
 	}
-	out, err := os.Create(filePath)
+	maxSize := ChartMaxSize()
+	tooLarge := &ChartRejectedError{TooLarge: true, Msg: fmt.Sprintf("chart文件不能超过 %dMB", maxSize>>20)}
+
+	tmp, err := os.CreateTemp(uploadDir(), ".upload-*.tgz")
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("创建chart文件失败 %v\n", err))
-		return errors.New(fmt.Sprintf("创建chart文件失败 %v\n", err))
+		return nil, errors.New(fmt.Sprintf("创建chart文件失败 %v\n", err))
 	}
-	defer out.Close()
+	// 保存成功后临时文件已被重命名，Remove 不会生效
+	defer os.Remove(tmp.Name())
 
-	_, err = io.Copy(out, file)
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(file, maxSize+1))
+	_ = tmp.Close()
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, tooLarge
+	}
 	if err != nil {
-		zap.L().Error(fmt.Sprintf("创建chart文件失败2 %v\n", err))
-		return errors.New(fmt.Sprintf("创建chart文件失败2 %v\n", err))
+		zap.L().Error(fmt.Sprintf("写入chart文件失败 %v\n", err))
+		return nil, errors.New(fmt.Sprintf("写入chart文件失败 %v\n", err))
 	}
-	return nil
+	if size > maxSize {
+		return nil, tooLarge
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	filename := digest + ".tgz"
+	if _, has, err := dao.Chart.HasFile(filename); err != nil {
+		return nil, err
+	} else if has {
+		return nil, &ChartRejectedError{Msg: "该chart文件已上传"}
+	}
+
+	chartRequested, err := loader.Load(tmp.Name())
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("解析chart文件失败 %v\n", err))
+		return nil, &ChartRejectedError{Msg: fmt.Sprintf("解析chart文件失败 %v\n", err)}
+	}
+	metadata := chartRequested.Metadata
+
+	_, has, err := dao.Chart.HasVersion(metadata.Name, metadata.Version)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		return nil, &ChartRejectedError{Msg: fmt.Sprintf("Chart %s 版本 %s 已存在", metadata.Name, metadata.Version)}
+	}
+
+	if result := action.NewLint().Run([]string{tmp.Name()}, nil); len(result.Errors) > 0 {
+		msgs := make([]string, 0, len(result.Errors))
+		for _, item := range result.Errors {
+			msgs = append(msgs, item.Error())
+		}
+		return nil, &ChartRejectedError{Msg: "chart lint 未通过, " + strings.Join(msgs, "; ")}
+	}
+
+	path, err := chartPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		zap.L().Error(fmt.Sprintf("保存chart文件失败 %v\n", err))
+		return nil, errors.New(fmt.Sprintf("保存chart文件失败 %v\n", err))
+	}
+
+	data := &model.Chart{
+		Name:       metadata.Name,
+		FileName:   filename,
+		IconUrl:    metadata.Icon,
+		Version:    metadata.Version,
+		AppVersion: metadata.AppVersion,
+		Describe:   metadata.Description,
+		Digest:     digest,
+	}
+	if err := dao.Chart.Add(data); err != nil {
+		_ = os.Remove(path)
+		return nil, chartRejected(err)
+	}
+	return data, nil
 }
 
 // ListCharts 获取 chart 列表
@@ -162,10 +481,12 @@ func (*helmStore) ListCharts(name string, page, limit int) (*dao.Charts, error)
 
 // DeleteChartFile chart 文件删除
 func (*helmStore) DeleteChartFile(chart string) error {
-	//路径拼接这种写法只支持mac或者linux，如果是windows，则要改成 filePath := dev-config.UploadPath + "\\" + chart
-	filePath := settings.Conf.UploadPath + chart
-	_, err := os.Stat(filePath)
-	if err != nil || os.IsNotExist(err) {
+	filePath, err := chartPath(chart)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(filePath)
+	if err != nil {
 		zap.L().Error(fmt.Sprintf("chart文件不存在 %v\n", err))
 		return errors.New(fmt.Sprintf("chart文件不存在 %v\n", err))
 	}
@@ -181,41 +502,58 @@ func (*helmStore) DeleteChartFile(chart string) error {
 
 // AddChart chart 新增
 func (*helmStore) AddChart(chart *model.Chart) error {
-	_, has, err := dao.Chart.Has(chart.Name)
+	if chart.FileName != "" {
+		if _, err := chartPath(chart.FileName); err != nil {
+			return err
+		}
+	}
+	_, has, err := dao.Chart.HasVersion(chart.Name, chart.Version)
 	if err != nil {
 		return err
 	}
 	if has {
-		return errors.New("该数据已存在，请重新添加")
+		return &ChartRejectedError{Msg: "该数据已存在，请重新添加"}
 	}
 
 	if err := dao.Chart.Add(chart); err != nil {
-		return err
+		return chartRejected(err)
 	}
 	return nil
 }
 
 // UpdateChart chart 更新
 func (h *helmStore) UpdateChart(chart *model.Chart) error {
-	oldChart, _, err := dao.Chart.Has(chart.Name)
+	oldChart, has, err := dao.Chart.Get(chart.ID)
 	if err != nil {
 		return err
 	}
-	fmt.Println(chart.FileName, oldChart.FileName)
+	if !has {
+		return errors.New("该Chart不存在")
+	}
 	//如果更新了新的上传文件，则老的文件要删除
 	if chart.FileName != "" && chart.FileName != oldChart.FileName {
+		if _, err := chartPath(chart.FileName); err != nil {
+			return err
+		}
 		err = h.DeleteChartFile(oldChart.FileName)
 		if err != nil {
 			return err
 		}
 	}
-	return dao.Chart.Update(chart)
+	return chartRejected(dao.Chart.Update(chart))
 }
 
 // DeleteChart chart 删除
 func (h *helmStore) DeleteChart(chart *model.Chart) error {
+	chart, has, err := dao.Chart.Get(chart.ID)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("该Chart不存在")
+	}
 	//删除文件
-	err := h.DeleteChartFile(chart.FileName)
+	err = h.DeleteChartFile(chart.FileName)
 	if err != nil {
 		return err
 	}
@@ -223,6 +561,61 @@ func (h *helmStore) DeleteChart(chart *model.Chart) error {
 	return dao.Chart.Delete(chart.ID)
 }
 
+// loadChart 加载chart，.tgz 文件从 UploadPath 中查找
+// chart 为内置仓库中的chart名称时，加载指定版本，version 为空时加载最新版本
+// chart 为 <仓库>/<chart> 或 oci:// 地址时，从远程仓库下载
+func loadChart(chart, version string) (*helmchart.Chart, error) {
+	if HelmRepo.IsRepoChart(chart) {
+		return HelmRepo.LoadChart(chart, version)
+	}
+
+	// 只允许加载 UploadPath 中的文件
+	fileName := chart
+	if !strings.HasSuffix(chart, ".tgz") {
+		data, err := HelmStore.GetChartVersion(chart, version)
+		if err != nil {
+			return nil, err
+		}
+		fileName = data.FileName
+	}
+	path, err := chartPath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	chartRequested, err := loader.Load(path)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("加载Chart文件失败, %v\n", err))
+	}
+	return chartRequested, nil
+}
+
+// chartPath 返回 UploadPath 中chart文件的路径，文件名不允许包含目录
+func chartPath(fileName string) (string, error) {
+	if fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." ||
+		strings.HasPrefix(fileName, ".") || !strings.HasSuffix(fileName, ".tgz") {
+		return "", errors.New(fmt.Sprintf("chart文件名 %s 不合法", fileName))
+	}
+	return filepath.Join(uploadDir(), fileName), nil
+}
+
+// uploadDir chart文件保存目录，未配置时为当前目录
+func uploadDir() string {
+	if settings.Conf.UploadPath == "" {
+		return "."
+	}
+	return settings.Conf.UploadPath
+}
+
+// ChartMaxSize 上传chart文件的大小上限，单位字节
+func ChartMaxSize() int64 {
+	if settings.Conf.ChartMaxSize <= 0 {
+		return 10 << 20
+	}
+	return int64(settings.Conf.ChartMaxSize) << 20
+}
+
 // constructReleaseElement release内容过滤
 func constructReleaseElement(r *release.Release, showStatus bool) *releaseElement {
 	element := &releaseElement{
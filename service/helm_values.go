@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/strvals"
+	"reflect"
+	"sort"
+)
+
+// values 差异类型
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ValueDiff values 中单个key的差异，key 为展开后的路径，如 image.tag、ports[0].name
+type ValueDiff struct {
+	Key  string      `json:"key"`
+	Type string      `json:"type"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// diffValues 展开两份 values 后逐个key对比，按key排序返回
+func diffValues(from, to map[string]interface{}) []*ValueDiff {
+	fromFlat := make(map[string]interface{})
+	toFlat := make(map[string]interface{})
+	flattenValues("", from, fromFlat)
+	flattenValues("", to, toFlat)
+
+	diffs := make([]*ValueDiff, 0)
+	for key, fromValue := range fromFlat {
+		toValue, ok := toFlat[key]
+		if !ok {
+			diffs = append(diffs, &ValueDiff{Key: key, Type: DiffRemoved, From: fromValue})
+			continue
+		}
+		if !reflect.DeepEqual(fromValue, toValue) {
+			diffs = append(diffs, &ValueDiff{Key: key, Type: DiffChanged, From: fromValue, To: toValue})
+		}
+	}
+	for key, toValue := range toFlat {
+		if _, ok := fromFlat[key]; !ok {
+			diffs = append(diffs, &ValueDiff{Key: key, Type: DiffAdded, To: toValue})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
+
+// flattenValues 将嵌套的 values 展开为 路径 => 值，空的 map 和数组保留为叶子节点
+func flattenValues(prefix string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			out[prefix] = v
+			return
+		}
+		for key, item := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenValues(path, item, out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[prefix] = v
+			return
+		}
+		for i, item := range v {
+			flattenValues(fmt.Sprintf("%s[%d]", prefix, i), item, out)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+// mergeValues 解析用户提供的 values YAML，再依次合并 --set 格式的覆盖值，如 image.tag=v1,replicaCount=2
+func mergeValues(values string, set []string) (map[string]interface{}, error) {
+	vals, err := chartutil.ReadValues([]byte(values))
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("解析values失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("解析values失败, %v\n", err))
+	}
+
+	for _, item := range set {
+		if err := strvals.ParseInto(item, vals); err != nil {
+			zap.L().Error(fmt.Sprintf("解析set参数失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("解析set参数失败, %v\n", err))
+		}
+	}
+	return vals, nil
+}
@@ -58,25 +58,24 @@ func (i *ingress) fromCells(cells []DataCell) []nwv1.Ingress {
 
 // GetIngresses 获取 Ingress 列表，支持过滤，排序，分页，
 // client用于选择哪个集群
-func (i *ingress) GetIngresses(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (ingressesResp *IngressesResp, err error) {
+func (i *ingress) GetIngresses(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (ingressesResp *IngressesResp, err error) {
 	//context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常
 	//用用法
 	//metav1.ListOptions{}用于过滤List数据，如使用label，field等
-	ingressList, err := client.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Ingress 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Ingress 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[nwv1.Ingress](K8s.cache(client), "ingresses", namespace)
+	if !ok {
+		ingressList, err := client.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Ingress 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Ingress 列表失败, %v\n", err))
+		}
+		items = ingressList.Items
 	}
 	//实例化dataSelector对象，把 p 结构体中获取到的 Ingress 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: i.toCells(ingressList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: i.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -94,6 +93,9 @@ func (i *ingress) GetIngresses(client *kubernetes.Clientset, filterName, namespa
 
 // GetIngressDetail 获取 Ingress 详情
 func (i *ingress) GetIngressDetail(client *kubernetes.Clientset, ingressName, namespace string) (Ingress *nwv1.Ingress, err error) {
+	if item, ok := cacheGet[nwv1.Ingress](K8s.cache(client), "ingresses", namespace, ingressName); ok {
+		return item, nil
+	}
 	ingresses, err := client.NetworkingV1().Ingresses(namespace).Get(context.TODO(), ingressName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Ingress 详情失败, %v\n", err))
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sort"
+)
+
+// resourceCache 集群资源的 informer 缓存，列表和详情优先从缓存读取
+type resourceCache struct {
+	factory   informers.SharedInformerFactory
+	informers map[string]cache.SharedIndexInformer
+}
+
+// CacheStatus 集群缓存的同步状态
+type CacheStatus struct {
+	Cluster   string          `json:"cluster"`
+	Synced    bool            `json:"synced"`
+	Resources map[string]bool `json:"resources"`
+}
+
+func newResourceCache(client kubernetes.Interface) *resourceCache {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	return &resourceCache{
+		factory: factory,
+		informers: map[string]cache.SharedIndexInformer{
+			"pods":         factory.Core().V1().Pods().Informer(),
+			"deployments":  factory.Apps().V1().Deployments().Informer(),
+			"daemonsets":   factory.Apps().V1().DaemonSets().Informer(),
+			"statefulsets": factory.Apps().V1().StatefulSets().Informer(),
+			"services":     factory.Core().V1().Services().Informer(),
+			"ingresses":    factory.Networking().V1().Ingresses().Informer(),
+			"configmaps":   factory.Core().V1().ConfigMaps().Informer(),
+			"secrets":      factory.Core().V1().Secrets().Informer(),
+			"pvcs":         factory.Core().V1().PersistentVolumeClaims().Informer(),
+			"pvs":          factory.Core().V1().PersistentVolumes().Informer(),
+			"nodes":        factory.Core().V1().Nodes().Informer(),
+			"namespaces":   factory.Core().V1().Namespaces().Informer(),
+			"jobs":         factory.Batch().V1().Jobs().Informer(),
+		},
+	}
+}
+
+// status 返回各资源的同步状态
+func (c *resourceCache) status(cluster string) *CacheStatus {
+	status := &CacheStatus{
+		Cluster:   cluster,
+		Synced:    true,
+		Resources: make(map[string]bool, len(c.informers)),
+	}
+	for name, informer := range c.informers {
+		synced := informer.HasSynced()
+		status.Resources[name] = synced
+		status.Synced = status.Synced && synced
+	}
+	return status
+}
+
+// cacheList 从缓存中获取资源列表，namespace 为空时返回所有名称空间
+// 缓存未同步时返回 false，由调用方直接请求 API Server
+func cacheList[T any](c *resourceCache, resource, namespace string) ([]T, bool) {
+	if c == nil {
+		return nil, false
+	}
+	informer, ok := c.informers[resource]
+	if !ok || !informer.HasSynced() {
+		return nil, false
+	}
+
+	var objs []interface{}
+	if namespace == "" {
+		objs = informer.GetStore().List()
+	} else {
+		var err error
+		objs, err = informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	items := make([]T, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.(*T))
+	}
+	return items, true
+}
+
+// cacheGet 从缓存中获取资源详情，返回深拷贝，调用方可以修改
+// 缓存未同步或不存在时返回 false，由调用方直接请求 API Server
+func cacheGet[T any](c *resourceCache, resource, namespace, name string) (*T, bool) {
+	if c == nil {
+		return nil, false
+	}
+	informer, ok := c.informers[resource]
+	if !ok || !informer.HasSynced() {
+		return nil, false
+	}
+
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	obj, exists, err := informer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, false
+	}
+	return any(obj.(runtime.Object).DeepCopyObject()).(*T), true
+}
+
+// cacheCount 从缓存中获取资源数量
+func cacheCount(c *resourceCache, resource string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+	informer, ok := c.informers[resource]
+	if !ok || !informer.HasSynced() {
+		return 0, false
+	}
+	return len(informer.GetStore().ListKeys()), true
+}
+
+// CacheStatus 返回集群缓存的同步状态，cluster 为空时返回所有集群
+func (k *k8s) CacheStatus(cluster string) []*CacheStatus {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	list := make([]*CacheStatus, 0, len(k.ClientMap))
+	for name, client := range k.ClientMap {
+		if cluster != "" && name != cluster {
+			continue
+		}
+		if rc, ok := k.cacheMap[client]; ok {
+			list = append(list, rc.status(name))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Cluster < list[j].Cluster
+	})
+	return list
+}
+
+// cache 根据 client 获取集群的资源缓存
+func (k *k8s) cache(client *kubernetes.Clientset) *resourceCache {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.cacheMap[client]
+}
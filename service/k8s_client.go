@@ -4,55 +4,316 @@ import (
 	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"kubea/settings"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"kubea/dao"
+	"kubea/model"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 var K8s k8s
 
 type k8s struct {
-	ClientMap   map[string]*kubernetes.Clientset
-	KubeConfMap map[string]string
+	mu        sync.RWMutex
+	ClientMap map[string]*kubernetes.Clientset
+	// 集群连接配置，helm 和 terminal 使用
+	confMap    map[string]*rest.Config
+	apiConfMap map[string]*clientcmdapi.Config
+	// 集群 informer 的停止信号
+	stopMap map[string]chan struct{}
+	// 集群资源缓存，按 client 查找
+	cacheMap map[*kubernetes.Clientset]*resourceCache
+	// 集群健康状态，由 ProbeTask 定时更新
+	healthMap map[string]*ClusterHealth
+}
+
+// ClusterHealth 集群健康状态
+type ClusterHealth struct {
+	Cluster   string    `json:"cluster"`
+	Healthy   bool      `json:"healthy"`
+	Version   string    `json:"version"`
+	Latency   int64     `json:"latency"` // 毫秒
+	LastError string    `json:"last_error"`
+	LastProbe time.Time `json:"last_probe"`
 }
 
 func (k *k8s) GetClient(cluster string) (*kubernetes.Clientset, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
 	client, ok := k.ClientMap[cluster]
 	if !ok {
-		zap.L().Error(fmt.Sprintf("集群：%s 不存在，无法获取 client\n", client))
-		return nil, errors.New(fmt.Sprintf("集群：%s 不存在，无法获取 client\n", client))
+		zap.L().Error(fmt.Sprintf("集群：%s 不存在，无法获取 client", cluster))
+		return nil, errors.New(fmt.Sprintf("集群：%s 不存在，无法获取 client", cluster))
+	}
+	if err := k.unhealthy(cluster); err != nil {
+		return nil, err
 	}
 	return client, nil
 }
 
-func (k *k8s) Init(cfg *settings.KubeConfigs) {
-	//mp := map[string]string{}
-	//
-	//if err := json.Unmarshal([]byte(config.Kubeconfigs), &mp); err != nil {
-	//	panic(fmt.Sprintf("Kubeconfigs 反序列化失败 %v\n", err))
-	//}
+// CheckHealth 集群被探测为不可用时返回错误，尚未探测的集群直接放行
+func (k *k8s) CheckHealth(cluster string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.unhealthy(cluster)
+}
+
+// unhealthy 需持有读锁
+func (k *k8s) unhealthy(cluster string) error {
+	if health, ok := k.healthMap[cluster]; ok && !health.Healthy {
+		return errors.New(fmt.Sprintf("集群：%s 不可用，%s", cluster, health.LastError))
+	}
+	return nil
+}
+
+// Health 返回集群健康状态，cluster 为空时返回所有集群
+func (k *k8s) Health(cluster string) []*ClusterHealth {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
 
-	mp := map[string]string{
-		//"DEV": cfg.DEV,
-		"TST": cfg.TST,
+	list := make([]*ClusterHealth, 0, len(k.healthMap))
+	for key, value := range k.healthMap {
+		if cluster != "" && key != cluster {
+			continue
+		}
+		item := *value
+		list = append(list, &item)
 	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Cluster < list[j].Cluster
+	})
+	return list
+}
 
+// ProbeTask 定时探测所有集群的 version 接口，interval 单位为秒
+func (k *k8s) ProbeTask(interval int) {
+	if interval <= 0 {
+		interval = 30
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		wg := sync.WaitGroup{}
+		for _, name := range k.Clusters() {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				k.probe(name)
+			}(name)
+		}
+		wg.Wait()
+		<-ticker.C
+	}
+}
+
+// probe 探测集群，记录版本、延迟和错误信息
+func (k *k8s) probe(cluster string) {
+	conf, err := k.GetConfig(cluster)
+	if err != nil {
+		return
+	}
+	conf.Timeout = 5 * time.Second
+
+	health := &ClusterHealth{Cluster: cluster, LastProbe: time.Now()}
+	start := time.Now()
+	dc, err := discovery.NewDiscoveryClientForConfig(conf)
+	if err == nil {
+		var version *k8sversion.Info
+		version, err = dc.ServerVersion()
+		if err == nil {
+			health.Healthy = true
+			health.Version = version.GitVersion
+		}
+	}
+	health.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		health.LastError = err.Error()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	// 探测期间集群已被卸载
+	if _, ok := k.ClientMap[cluster]; !ok {
+		return
+	}
+	old, ok := k.healthMap[cluster]
+	if ok && old.Healthy && !health.Healthy {
+		zap.L().Error(fmt.Sprintf("集群 %s: 不可用 %s", cluster, health.LastError))
+	}
+	if ok && !old.Healthy && health.Healthy {
+		zap.L().Info(fmt.Sprintf("集群 %s: 恢复可用", cluster))
+	}
+	k.healthMap[cluster] = health
+}
+
+// GetConfig 获取集群的 rest 配置
+func (k *k8s) GetConfig(cluster string) (*rest.Config, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	conf, ok := k.confMap[cluster]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("集群：%s 不存在，无法获取配置", cluster))
+	}
+	return rest.CopyConfig(conf), nil
+}
+
+// GetApiConfig 获取集群的 kubeconfig 配置
+func (k *k8s) GetApiConfig(cluster string) (*clientcmdapi.Config, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	conf, ok := k.apiConfMap[cluster]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("集群：%s 不存在，无法获取配置", cluster))
+	}
+	return conf.DeepCopy(), nil
+}
+
+// Clusters 返回已加载的集群名称
+func (k *k8s) Clusters() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	list := make([]string, 0, len(k.ClientMap))
+	for key := range k.ClientMap {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Init 从数据库加载集群，配置文件中的 kube_configs 仅在数据库中不存在时导入
+func (k *k8s) Init(seeds map[string]string) {
 	k.ClientMap = map[string]*kubernetes.Clientset{}
+	k.confMap = map[string]*rest.Config{}
+	k.apiConfMap = map[string]*clientcmdapi.Config{}
+	k.stopMap = map[string]chan struct{}{}
+	k.healthMap = map[string]*ClusterHealth{}
+	k.cacheMap = map[*kubernetes.Clientset]*resourceCache{}
 
-	k.KubeConfMap = mp
-	for key, value := range mp {
-		conf, err := clientcmd.BuildConfigFromFlags("", value)
+	for name, path := range seeds {
+		name = strings.ToUpper(name)
+		_, has, err := dao.Cluster.Has(name)
+		if err != nil || has {
+			continue
+		}
+		content, err := os.ReadFile(path)
 		if err != nil {
-			zap.L().Panic(fmt.Sprintf("集群 %s: 创建 K8S 配置失败 %v\n", key, err))
-			//panic(fmt.Sprintf("集群 %s: 创建 K8S 配置失败 %v\n", key, err))
+			zap.L().Error(fmt.Sprintf("集群 %s: 读取 kubeconfig 失败 %v", name, err))
+			continue
+		}
+		if err := dao.Cluster.Add(&model.Cluster{Name: name, Kubeconfig: string(content)}); err != nil {
+			continue
 		}
-		clientSet, err := kubernetes.NewForConfig(conf)
+		zap.L().Info(fmt.Sprintf("集群 %s: 从配置文件导入成功", name))
+	}
+
+	clusters, err := dao.Cluster.GetAll()
+	if err != nil {
+		return
+	}
+	// 加载失败的集群只记录日志，不影响其他集群
+	for _, item := range clusters {
+		if err := k.Add(item); err != nil {
+			zap.L().Error(err.Error())
+		}
+	}
+}
+
+// Add 加载集群，已存在的会先卸载再重新加载
+func (k *k8s) Add(c *model.Cluster) error {
+	apiConf, conf, clientSet, err := newClient(c)
+	if err != nil {
+		return err
+	}
+
+	k.Remove(c.Name)
+
+	stopCh := make(chan struct{})
+	rc := newResourceCache(clientSet)
+	k.mu.Lock()
+	k.ClientMap[c.Name] = clientSet
+	k.confMap[c.Name] = conf
+	k.apiConfMap[c.Name] = apiConf
+	k.stopMap[c.Name] = stopCh
+	k.cacheMap[clientSet] = rc
+	k.mu.Unlock()
+
+	go k.probe(c.Name)
+	// 资源缓存和 event 监听共用同一个 informerFactory
+	rc.factory.Start(stopCh)
+	go Event.WatchEventTask(c.Name, rc.factory, stopCh)
+	zap.L().Info(fmt.Sprintf("集群 %s: 创建 K8sClient 成功", c.Name))
+	return nil
+}
+
+// newClient 根据集群凭证创建 client，不加载到 ClientMap，也用于保存前校验凭证
+func newClient(c *model.Cluster) (*clientcmdapi.Config, *rest.Config, *kubernetes.Clientset, error) {
+	apiConf, err := BuildApiConfig(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conf, err := clientcmd.NewDefaultClientConfig(*apiConf, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, nil, nil, errors.New(fmt.Sprintf("集群 %s: 创建 K8S 配置失败 %v", c.Name, err))
+	}
+	clientSet, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		return nil, nil, nil, errors.New(fmt.Sprintf("集群 %s: 创建 K8sClient 失败 %v", c.Name, err))
+	}
+	return apiConf, conf, clientSet, nil
+}
+
+// Remove 卸载集群，并停止 informer
+func (k *k8s) Remove(cluster string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if stopCh, ok := k.stopMap[cluster]; ok {
+		close(stopCh)
+	}
+	if client, ok := k.ClientMap[cluster]; ok {
+		delete(k.cacheMap, client)
+	}
+	delete(k.ClientMap, cluster)
+	delete(k.confMap, cluster)
+	delete(k.apiConfMap, cluster)
+	delete(k.stopMap, cluster)
+	delete(k.healthMap, cluster)
+}
+
+// BuildApiConfig 根据集群记录生成 kubeconfig 配置
+func BuildApiConfig(c *model.Cluster) (*clientcmdapi.Config, error) {
+	if c.Kubeconfig != "" {
+		conf, err := clientcmd.Load([]byte(c.Kubeconfig))
 		if err != nil {
-			zap.L().Panic(fmt.Sprintf("集群 %s: 创建 K8sClient 失败 %v\n", key, err))
-			//panic(fmt.Sprintf("集群 %s: 创建 K8sClient 失败 %v\n", key, err))
+			return nil, errors.New(fmt.Sprintf("集群 %s: 解析 kubeconfig 失败 %v", c.Name, err))
 		}
+		return conf, nil
+	}
+	if c.ApiServer == "" || c.Token == "" {
+		return nil, errors.New(fmt.Sprintf("集群 %s: 请填写 kubeconfig 或 ApiServer 和 Token", c.Name))
+	}
 
-		k.ClientMap[key] = clientSet
-		zap.L().Info(fmt.Sprintf("集群 %s: 创建 K8sClient 成功", key))
+	conf := clientcmdapi.NewConfig()
+	conf.Clusters[c.Name] = &clientcmdapi.Cluster{
+		Server:                   c.ApiServer,
+		CertificateAuthorityData: []byte(c.CaData),
+		InsecureSkipTLSVerify:    c.Insecure,
 	}
+	conf.AuthInfos[c.Name] = &clientcmdapi.AuthInfo{Token: c.Token}
+	conf.Contexts[c.Name] = &clientcmdapi.Context{Cluster: c.Name, AuthInfo: c.Name}
+	conf.CurrentContext = c.Name
+	return conf, nil
 }
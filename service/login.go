@@ -6,40 +6,166 @@ import (
 	"kubea/dao"
 	"kubea/model"
 	"kubea/settings"
+	"kubea/utils"
+	"time"
 )
 
 var Login login
 
 type login struct{}
 
+// TokenPair 登录或刷新后返回给前端的token
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
 // Auth 验证账号密码
 func (l *login) Auth(username, password string) (*model.User, error) {
 	if username == settings.Conf.Admin.UserName {
 		if password != settings.Conf.Admin.PassWord {
 			zap.L().Error("登录失败, 用户名或密码错误")
 			return nil, errors.New("登录失败, 用户名或密码错误")
-		} else {
-			return &model.User{
-				Role: 1,
-			}, nil
 		}
-	} else {
+		return &model.User{
+			UserName: username,
+			Role:     1,
+		}, nil
+	}
+
+	data, has, err := dao.User.Has(username)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("登录失败, 用户名或密码错误")
+	}
+
+	_, err = User.VerifyPassword(data.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Token 为用户签发access token和refresh token
+func (l *login) Token(u *model.User) (*TokenPair, error) {
+	token, claims, err := utils.JWTToken.GenToken(u.ID, u.UserName, u.Role, utils.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, _, err := utils.JWTToken.GenToken(u.ID, u.UserName, u.Role, utils.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
 
-		data, has, err := dao.User.Has(username)
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiresAt,
+	}, nil
+}
 
+// Refresh 使用refresh token换取新的token，旧的refresh token随即注销
+func (l *login) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, err := utils.JWTToken.ParseToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != utils.RefreshToken {
+		return nil, errors.New("TokenInvalid")
+	}
+	revoked, err := l.Revoked(claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("TokenRevoked")
+	}
+
+	// 重新查询用户信息，角色变更后刷新即可生效
+	u := &model.User{
+		UserName: claims.Username,
+		Role:     1,
+	}
+	if claims.Username != settings.Conf.Admin.UserName {
+		data, has, err := dao.User.Has(claims.Username)
 		if err != nil {
 			return nil, err
 		}
 		if !has {
-			return nil, errors.New("登录失败, 用户名或密码错误")
+			return nil, errors.New("用户不存在")
 		}
+		u = data
+	}
 
-		_, err = User.VerifyPassword(data.Password, password)
-		if err != nil {
-			return nil, err
+	if err := l.revoke(claims); err != nil {
+		return nil, err
+	}
+	return l.Token(u)
+}
+
+// Logout 注销当前的access token，若携带了refresh token则一并注销
+func (l *login) Logout(claims *utils.CustomClaims, refreshToken string) error {
+	if err := l.revoke(claims); err != nil {
+		return err
+	}
+
+	if refreshToken != "" {
+		refreshClaims, err := utils.JWTToken.ParseToken(refreshToken)
+		if err == nil && refreshClaims.Username == claims.Username {
+			if err := l.revoke(refreshClaims); err != nil {
+				return err
+			}
 		}
-		return data, nil
+	}
+
+	// 顺带清理已过期的注销记录
+	return dao.Token.Clean()
+}
 
+// Verify 校验 access token，返回解析出的用户信息，供 JWTAuth 及 websocket 使用
+func (*login) Verify(token string) (*utils.CustomClaims, error) {
+	if token == "" {
+		return nil, errors.New("请求未携带token，无权限访问")
 	}
-	return nil, nil
+
+	claims, err := utils.JWTToken.ParseToken(token)
+	if err != nil {
+		//token延期错误
+		if err.Error() == "TokenExpired" {
+			return nil, errors.New("授权已过期")
+		}
+		return nil, err
+	}
+
+	// refresh token 只能用于换取新的token
+	if claims.Type != utils.AccessToken {
+		return nil, errors.New("TokenInvalid")
+	}
+
+	// 已退出登录的token
+	revoked, err := Login.Revoked(claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("授权已注销")
+	}
+	return claims, nil
+}
+
+// Revoked 判断token是否已注销
+func (*login) Revoked(tokenID string) (bool, error) {
+	return dao.Token.Revoked(tokenID)
+}
+
+// revoke 将token加入注销列表
+func (*login) revoke(claims *utils.CustomClaims) error {
+	return dao.Token.Revoke(&model.RevokedToken{
+		TokenID:   claims.Id,
+		UserName:  claims.Username,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	})
 }
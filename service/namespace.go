@@ -39,11 +39,16 @@ func (n *namespace) fromCells(cells []DataCell) []corev1.Namespace {
 }
 
 // GetNamespaces 获取 Namespace 列表
-func (n *namespace) GetNamespaces(client *kubernetes.Clientset, filterName string, limit, page int) (namespacesResp *NamespacesResp, err error) {
-	namespaceList, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Namespace 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Namespace 列表失败, %v\n", err))
+func (n *namespace) GetNamespaces(client *kubernetes.Clientset, query *DataSelectQuery) (namespacesResp *NamespacesResp, err error) {
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.Namespace](K8s.cache(client), "namespaces", "")
+	if !ok {
+		namespaceList, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Namespace 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Namespace 列表失败, %v\n", err))
+		}
+		items = namespaceList.Items
 	}
 	// 返回给客户端的名称空间
 	filterNamespaces := []string{"dev", "d1", "d2", "test", "t1", "t2", "t3"}
@@ -54,7 +59,7 @@ func (n *namespace) GetNamespaces(client *kubernetes.Clientset, filterName strin
 		filteredNamespaces[name] = true
 	}
 	selectedNamespaces := make([]corev1.Namespace, 0)
-	for _, ns := range namespaceList.Items {
+	for _, ns := range items {
 		if filteredNamespaces[ns.Name] {
 			selectedNamespaces = append(selectedNamespaces, ns)
 		}
@@ -62,13 +67,7 @@ func (n *namespace) GetNamespaces(client *kubernetes.Clientset, filterName strin
 
 	selectableData := &dataSelector{
 		GenericDataList: n.toCells(selectedNamespaces),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		dataSelectQuery: query,
 	}
 
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
@@ -88,6 +87,9 @@ func (n *namespace) GetNamespaces(client *kubernetes.Clientset, filterName strin
 
 // GetNamespaceDetail 获取 Namespace 详情
 func (n *namespace) GetNamespaceDetail(client *kubernetes.Clientset, namespaceName string) (namespace *corev1.Namespace, err error) {
+	if item, ok := cacheGet[corev1.Namespace](K8s.cache(client), "namespaces", "", namespaceName); ok {
+		return item, nil
+	}
 	namespaces, err := client.CoreV1().Namespaces().Get(context.TODO(), namespaceName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Namespace 详情失败, %v\n", err))
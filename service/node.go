@@ -39,21 +39,20 @@ func (n *node) fromCells(cells []DataCell) []corev1.Node {
 }
 
 // GetNodes 获取 Node 列表
-func (n *node) GetNodes(client *kubernetes.Clientset, filterName string, limit, page int) (nodesResp *NodesResp, err error) {
-	nodeList, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Node 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Node 列表失败, %v\n", err))
+func (n *node) GetNodes(client *kubernetes.Clientset, query *DataSelectQuery) (nodesResp *NodesResp, err error) {
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.Node](K8s.cache(client), "nodes", "")
+	if !ok {
+		nodeList, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Node 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Node 列表失败, %v\n", err))
+		}
+		items = nodeList.Items
 	}
 	selectableData := &dataSelector{
-		GenericDataList: n.toCells(nodeList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: n.toCells(items),
+		dataSelectQuery: query,
 	}
 
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
@@ -72,6 +71,9 @@ func (n *node) GetNodes(client *kubernetes.Clientset, filterName string, limit,
 
 // GetNodeDetail 获取 Node 详情
 func (n *node) GetNodeDetail(client *kubernetes.Clientset, nodeName string) (node *corev1.Node, err error) {
+	if item, ok := cacheGet[corev1.Node](K8s.cache(client), "nodes", "", nodeName); ok {
+		return item, nil
+	}
 	node, err = client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Node 详情失败, %v\n", err))
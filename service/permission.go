@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+)
+
+var ApiPermission apiPermission
+
+type apiPermission struct{}
+
+// List 返回接口权限列表
+func (*apiPermission) List(name string, page, limit int) (*dao.Permissions, error) {
+	return dao.Permission.List(name, page, limit)
+}
+
+// GetAll 查询所有接口权限，角色绑定权限时使用
+func (*apiPermission) GetAll() ([]*model.Permission, error) {
+	return dao.Permission.GetAll()
+}
+
+// Add 新增接口权限
+func (*apiPermission) Add(p *model.Permission) error {
+	if p.Method == "" || p.Path == "" {
+		return errors.New("请填写请求方法和路径")
+	}
+	_, has, err := dao.Permission.Has(p.Method, p.Path)
+	if err != nil {
+		return err
+	}
+	if has {
+		return errors.New("该接口权限已存在，请重新添加")
+	}
+
+	return dao.Permission.Add(p)
+}
+
+// Update 更新接口权限
+func (*apiPermission) Update(p *model.Permission) error {
+	return dao.Permission.Update(p)
+}
+
+// Delete 删除接口权限
+func (*apiPermission) Delete(id uint) error {
+	return dao.Permission.Delete(id)
+}
+
+// Sync 将路由表同步为接口权限，已存在的不做修改，单个接口失败时继续同步其余接口，返回第一个错误
+func (*apiPermission) Sync(routes []*model.Permission) error {
+	var firstErr error
+	for _, item := range routes {
+		_, has, err := dao.Permission.Has(item.Method, item.Path)
+		if err == nil && !has {
+			err = dao.Permission.Add(item)
+			if err == nil {
+				zap.L().Info("新增接口权限: " + item.Method + " " + item.Path)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Check 校验角色是否有权限访问该接口，超级管理员拥有所有权限
+func (*apiPermission) Check(roleID uint, method, path string) (bool, error) {
+	if roleID == 1 {
+		return true, nil
+	}
+	return dao.Permission.Allowed(roleID, method, path)
+}
+
+// GetRole 查询角色绑定的接口权限ID
+func (*apiPermission) GetRole(roleID uint) ([]uint, error) {
+	data, err := dao.Permission.GetRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(data))
+	for _, item := range data {
+		ids = append(ids, item.PermissionID)
+	}
+	return ids, nil
+}
+
+// UpdateRole 更新角色绑定的接口权限
+func (p *apiPermission) UpdateRole(roleID uint, permissionIDs []uint) error {
+	if roleID == 1 {
+		return errors.New("超级管理员拥有所有权限，无需绑定")
+	}
+
+	oldIDs, err := p.GetRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	// 需要新增的数据
+	for _, id := range p.difference(oldIDs, permissionIDs) {
+		err := dao.Permission.AddRole(&model.RolePermission{
+			RoleID:       roleID,
+			PermissionID: id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// 需要删除的数据
+	for _, id := range p.difference(permissionIDs, oldIDs) {
+		if err := dao.Permission.DeleteRole(roleID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// difference 计算 slice2 中存在但在 slice1 中不存在的元素
+func (*apiPermission) difference(slice1, slice2 []uint) []uint {
+	sliceMap := make(map[uint]struct{})
+	for _, v := range slice1 {
+		sliceMap[v] = struct{}{}
+	}
+
+	difference := make([]uint, 0)
+	for _, v := range slice2 {
+		if _, exists := sliceMap[v]; !exists {
+			difference = append(difference, v)
+			sliceMap[v] = struct{}{}
+		}
+	}
+	return difference
+}
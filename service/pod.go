@@ -44,25 +44,24 @@ func (p *pod) fromCells(cells []DataCell) []corev1.Pod {
 
 // GetPods 获取pod列表，支持过滤，排序，分页，
 // client用于选择哪个集群
-func (p *pod) GetPods(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (podsResp *PodsResp, err error) {
+func (p *pod) GetPods(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (podsResp *PodsResp, err error) {
 	//context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常
 	//用用法
 	//metav1.ListOptions{}用于过滤List数据，如使用label，field等
-	podList, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Pod 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Pod 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.Pod](K8s.cache(client), "pods", namespace)
+	if !ok {
+		podList, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Pod 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Pod 列表失败, %v\n", err))
+		}
+		items = podList.Items
 	}
 	//实例化dataSelector对象，把 p 结构体中获取到的 Pod 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: p.toCells(podList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: p.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -80,6 +79,9 @@ func (p *pod) GetPods(client *kubernetes.Clientset, filterName, namespace string
 
 // GetPodDetail 获取 Pod 详情
 func (p *pod) GetPodDetail(client *kubernetes.Clientset, podName, namespace string) (pod *corev1.Pod, err error) {
+	if item, ok := cacheGet[corev1.Pod](K8s.cache(client), "pods", namespace, podName); ok {
+		return item, nil
+	}
 	pod, err = client.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Pod 详情失败, %v\n", err))
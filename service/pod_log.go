@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubea/settings"
+)
+
+// PodLogQuery 实时日志的查询条件
+// SinceSeconds 和 SinceTime(RFC3339) 二选一，都未指定且未指定 TailLines 时返回最后 PodLogTailLine 行
+// Previous 查看上一次退出的容器日志，用于排查崩溃重启的容器
+// Grep 为正则表达式，只返回匹配的行
+type PodLogQuery struct {
+	Cluster       string `form:"cluster"`
+	Namespace     string `form:"namespace"`
+	PodName       string `form:"pod_name"`
+	ContainerName string `form:"container_name"`
+	Follow        bool   `form:"follow"`
+	SinceSeconds  int64  `form:"since_seconds"`
+	SinceTime     string `form:"since_time"`
+	Previous      bool   `form:"previous"`
+	Timestamps    bool   `form:"timestamps"`
+	TailLines     int64  `form:"tail_lines"`
+	Grep          string `form:"grep"`
+}
+
+// PodLogStream 容器日志流，按行读取并过滤
+type PodLogStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	grep   *regexp.Regexp
+}
+
+// PodLogMessage websocket 日志消息
+// 服务端返回 log、error、end、pong，客户端可发送 ping 保持连接
+type PodLogMessage struct {
+	Operation string `json:"operation"`
+	Data      string `json:"data"`
+}
+
+// 日志 websocket 连接写超时
+const podLogWriteTimeout = 10 * time.Second
+
+// parsePodLogQuery 解析 websocket 请求的查询条件
+func parsePodLogQuery(form url.Values) (*PodLogQuery, error) {
+	q := &PodLogQuery{
+		Cluster:       form.Get("cluster"),
+		Namespace:     form.Get("namespace"),
+		PodName:       form.Get("pod_name"),
+		ContainerName: form.Get("container_name"),
+		SinceTime:     form.Get("since_time"),
+		Grep:          form.Get("grep"),
+	}
+	for key, value := range map[string]*bool{"follow": &q.Follow, "previous": &q.Previous, "timestamps": &q.Timestamps} {
+		if form.Get(key) == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(form.Get(key))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("参数 %s 格式错误, %v", key, err))
+		}
+		*value = b
+	}
+	for key, value := range map[string]*int64{"since_seconds": &q.SinceSeconds, "tail_lines": &q.TailLines} {
+		if form.Get(key) == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(form.Get(key), 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("参数 %s 格式错误, %v", key, err))
+		}
+		*value = n
+	}
+	return q, nil
+}
+
+// options 转换为 k8s 的日志参数
+func (q *PodLogQuery) options() (*corev1.PodLogOptions, error) {
+	if q.PodName == "" || q.Namespace == "" {
+		return nil, errors.New("pod_name、namespace 不能为空")
+	}
+	if q.SinceSeconds < 0 || q.TailLines < 0 {
+		return nil, errors.New("since_seconds、tail_lines 不能小于 0")
+	}
+	if q.SinceSeconds > 0 && q.SinceTime != "" {
+		return nil, errors.New("since_seconds 和 since_time 只能指定一个")
+	}
+
+	option := &corev1.PodLogOptions{
+		Container:  q.ContainerName,
+		Follow:     q.Follow,
+		Previous:   q.Previous,
+		Timestamps: q.Timestamps,
+	}
+	if q.SinceSeconds > 0 {
+		option.SinceSeconds = &q.SinceSeconds
+	}
+	if q.SinceTime != "" {
+		t, err := time.Parse(time.RFC3339, q.SinceTime)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("since_time 格式错误，应为 RFC3339, %v", err))
+		}
+		since := metav1.NewTime(t)
+		option.SinceTime = &since
+	}
+	switch {
+	case q.TailLines > 0:
+		option.TailLines = &q.TailLines
+	case option.SinceSeconds == nil && option.SinceTime == nil:
+		lineLimit := int64(settings.Conf.PodLogTailLine)
+		option.TailLines = &lineLimit
+	}
+	return option, nil
+}
+
+// OpenLog 打开容器日志流，ctx 取消后结束读取
+func (p *pod) OpenLog(ctx context.Context, client *kubernetes.Clientset, q *PodLogQuery) (*PodLogStream, error) {
+	option, err := q.options()
+	if err != nil {
+		return nil, err
+	}
+	var grep *regexp.Regexp
+	if q.Grep != "" {
+		if grep, err = regexp.Compile(q.Grep); err != nil {
+			return nil, errors.New(fmt.Sprintf("grep 表达式错误, %v", err))
+		}
+	}
+
+	body, err := client.CoreV1().Pods(q.Namespace).GetLogs(q.PodName, option).Stream(ctx)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("获取PodLog失败, %v\n", err))
+		return nil, errors.New(fmt.Sprintf("获取PodLog失败, %v\n", err))
+	}
+	return &PodLogStream{
+		body:   body,
+		reader: bufio.NewReader(body),
+		grep:   grep,
+	}, nil
+}
+
+// Next 读取下一行匹配的日志，不含换行符，读取完毕返回 io.EOF
+func (s *PodLogStream) Next() (string, error) {
+	for {
+		line, err := s.reader.ReadString('\n')
+		if line == "" && err != nil {
+			return "", err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if s.grep == nil || s.grep.MatchString(line) {
+			return line, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// Close 关闭日志流
+func (s *PodLogStream) Close() error {
+	return s.body.Close()
+}
+
+// WsLogHandler 通过 websocket 实时查看容器日志
+// token 通过 ?token= 传递，需要拥有集群名称空间的读权限
+func (p *pod) WsLogHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q, err := parsePodLogQuery(r.Form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	//校验token及集群名称空间权限，失败时不升级协议
+	claims, err := Login.Verify(r.Form.Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	allowed, err := Scope.Check(claims.Role, q.Cluster, q.Namespace, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("无权限访问集群 %s 名称空间 %s", q.Cluster, q.Namespace), http.StatusForbidden)
+		return
+	}
+
+	client, err := K8s.GetClient(q.Cluster)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	//打开日志流，连接断开时取消
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stream, err := p.OpenLog(ctx, client, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer stream.Close()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		zap.L().Error("upgrade pod log websocket failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// gorilla websocket 不支持并发写
+	var mu sync.Mutex
+	send := func(msg PodLogMessage) error {
+		data, _ := json.Marshal(msg)
+		mu.Lock()
+		defer mu.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(podLogWriteTimeout))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	//读取客户端消息，回复心跳，连接断开时结束日志流
+	go func() {
+		defer cancel()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg PodLogMessage
+			if json.Unmarshal(message, &msg) == nil && msg.Operation == "ping" {
+				_ = send(PodLogMessage{Operation: "pong"})
+			}
+		}
+	}()
+
+	for {
+		line, err := stream.Next()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				_ = send(PodLogMessage{Operation: "error", Data: err.Error()})
+			}
+			_ = send(PodLogMessage{Operation: "end"})
+			return
+		}
+		if err := send(PodLogMessage{Operation: "log", Data: line}); err != nil {
+			return
+		}
+	}
+}
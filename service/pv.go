@@ -39,21 +39,20 @@ func (p *pv) fromCells(cells []DataCell) []corev1.PersistentVolume {
 }
 
 // GetPvs 获取 Pv 列表
-func (p *pv) GetPvs(client *kubernetes.Clientset, filterName string, limit, page int) (pvsResp *PvsResp, err error) {
-	pvList, err := client.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 PV 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 PV 列表失败, %v\n", err))
+func (p *pv) GetPvs(client *kubernetes.Clientset, query *DataSelectQuery) (pvsResp *PvsResp, err error) {
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.PersistentVolume](K8s.cache(client), "pvs", "")
+	if !ok {
+		pvList, err := client.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 PV 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 PV 列表失败, %v\n", err))
+		}
+		items = pvList.Items
 	}
 	selectableData := &dataSelector{
-		GenericDataList: p.toCells(pvList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: p.toCells(items),
+		dataSelectQuery: query,
 	}
 
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
@@ -72,6 +71,9 @@ func (p *pv) GetPvs(client *kubernetes.Clientset, filterName string, limit, page
 
 // GetPvDetail 获取 Pv 详情
 func (p *pv) GetPvDetail(client *kubernetes.Clientset, pvName string) (pvs *corev1.PersistentVolume, err error) {
+	if item, ok := cacheGet[corev1.PersistentVolume](K8s.cache(client), "pvs", "", pvName); ok {
+		return item, nil
+	}
 	pvDetail, err := client.CoreV1().PersistentVolumes().Get(context.TODO(), pvName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 PV 详情失败, %v\n", err))
@@ -40,23 +40,22 @@ func (c *pvc) fromCells(cells []DataCell) []corev1.PersistentVolumeClaim {
 }
 
 // GetPvcs 获取 PVC 列表
-func (c *pvc) GetPvcs(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (pvcsResp *PvcsResp, err error) {
+func (c *pvc) GetPvcs(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (pvcsResp *PvcsResp, err error) {
 	// context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常用用法
-	pvcList, err := client.CoreV1().PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 PVC 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 PVC 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.PersistentVolumeClaim](K8s.cache(client), "pvcs", namespace)
+	if !ok {
+		pvcList, err := client.CoreV1().PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 PVC 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 PVC 列表失败, %v\n", err))
+		}
+		items = pvcList.Items
 	}
 	//实例化dataSelector对象，把 d 结构体中获取到的 StatefulSet 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: c.toCells(pvcList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: c.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -74,6 +73,9 @@ func (c *pvc) GetPvcs(client *kubernetes.Clientset, filterName, namespace string
 
 // GetPvcDetail 获取 PVC 详情
 func (c *pvc) GetPvcDetail(client *kubernetes.Clientset, pvcName, namespace string) (pvc *corev1.PersistentVolumeClaim, err error) {
+	if item, ok := cacheGet[corev1.PersistentVolumeClaim](K8s.cache(client), "pvcs", namespace, pvcName); ok {
+		return item, nil
+	}
 	pvcDetail, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), pvcName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 PVC 详情失败, %v\n", err))
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"kubea/dao"
+	"kubea/model"
+)
+
+var Scope scope
+
+type scope struct{}
+
+// List 返回名称空间权限列表
+func (*scope) List(roleID uint, cluster string, page, limit int) (*dao.Scopes, error) {
+	return dao.Scope.List(roleID, cluster, page, limit)
+}
+
+// Add 新增名称空间权限
+func (s *scope) Add(rs *model.RoleScope) error {
+	if err := s.validate(rs); err != nil {
+		return err
+	}
+	_, has, err := dao.Scope.Has(rs.RoleID, rs.Cluster, rs.Namespace)
+	if err != nil {
+		return err
+	}
+	if has {
+		return errors.New("该名称空间权限已存在，请重新添加")
+	}
+
+	return dao.Scope.Add(rs)
+}
+
+// Update 更新名称空间权限
+func (s *scope) Update(rs *model.RoleScope) error {
+	if err := s.validate(rs); err != nil {
+		return err
+	}
+	return dao.Scope.Update(rs)
+}
+
+// Delete 删除名称空间权限
+func (*scope) Delete(id uint) error {
+	return dao.Scope.Delete(id)
+}
+
+// Check 校验角色对集群名称空间的访问权限
+// namespace 为空表示集群级别的资源，需要拥有 * 的权限
+func (*scope) Check(roleID uint, cluster, namespace string, write bool) (bool, error) {
+	if roleID == 1 {
+		return true, nil
+	}
+
+	data, err := dao.Scope.GetCluster(roleID, cluster)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range data {
+		if item.Namespace != "*" && item.Namespace != namespace {
+			continue
+		}
+		if !write || item.Access == model.ScopeWrite {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Namespaces 返回角色在集群中可读的名称空间，nil 表示不限制
+func (*scope) Namespaces(roleID uint, cluster string) ([]string, error) {
+	if roleID == 1 {
+		return nil, nil
+	}
+
+	data, err := dao.Scope.GetCluster(roleID, cluster)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(data))
+	for _, item := range data {
+		if item.Namespace == "*" {
+			return nil, nil
+		}
+		namespaces = append(namespaces, item.Namespace)
+	}
+	return namespaces, nil
+}
+
+// validate 校验名称空间权限参数
+func (*scope) validate(rs *model.RoleScope) error {
+	if rs.RoleID == 0 || rs.Cluster == "" || rs.Namespace == "" {
+		return errors.New("请填写角色、集群和名称空间信息")
+	}
+	if rs.Access != model.ScopeRead && rs.Access != model.ScopeWrite {
+		return errors.New("访问级别只能为 read 或 write")
+	}
+	return nil
+}
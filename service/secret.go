@@ -40,23 +40,22 @@ func (c *secret) fromCells(cells []DataCell) []corev1.Secret {
 }
 
 // GetSecrets 获取 Secret 列表
-func (c *secret) GetSecrets(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (secretsResp *SecretsResp, err error) {
+func (c *secret) GetSecrets(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (secretsResp *SecretsResp, err error) {
 	// context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常用用法
-	secretList, err := client.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Secret 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Secret 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.Secret](K8s.cache(client), "secrets", namespace)
+	if !ok {
+		secretList, err := client.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Secret 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Secret 列表失败, %v\n", err))
+		}
+		items = secretList.Items
 	}
 	//实例化dataSelector对象，把 d 结构体中获取到的 StatefulSet 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: c.toCells(secretList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: c.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -74,6 +73,9 @@ func (c *secret) GetSecrets(client *kubernetes.Clientset, filterName, namespace
 
 // GetSecretDetail 获取 Secret 详情
 func (c *secret) GetSecretDetail(client *kubernetes.Clientset, secretName, namespace string) (secret *corev1.Secret, err error) {
+	if item, ok := cacheGet[corev1.Secret](K8s.cache(client), "secrets", namespace, secretName); ok {
+		return item, nil
+	}
 	secretDetail, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Secret 详情失败, %v\n", err))
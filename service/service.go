@@ -53,25 +53,24 @@ func (s *servicev1) fromCells(cells []DataCell) []corev1.Service {
 
 // GetServices 获取Service列表，支持过滤，排序，分页，
 // client用于选择哪个集群
-func (s *servicev1) GetServices(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (servicesResp *ServicesResp, err error) {
+func (s *servicev1) GetServices(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (servicesResp *ServicesResp, err error) {
 	//context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常
 	//用用法
 	//metav1.ListOptions{}用于过滤List数据，如使用label，field等
-	serviceList, err := client.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 Service 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 Service 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[corev1.Service](K8s.cache(client), "services", namespace)
+	if !ok {
+		serviceList, err := client.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 Service 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 Service 列表失败, %v\n", err))
+		}
+		items = serviceList.Items
 	}
 	//实例化dataSelector对象，把 p 结构体中获取到的 Service 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: s.toCells(serviceList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: s.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -89,6 +88,9 @@ func (s *servicev1) GetServices(client *kubernetes.Clientset, filterName, namesp
 
 // GetServiceDetail 获取 Service 详情
 func (s *servicev1) GetServiceDetail(client *kubernetes.Clientset, serviceName, namespace string) (service *corev1.Service, err error) {
+	if item, ok := cacheGet[corev1.Service](K8s.cache(client), "services", namespace, serviceName); ok {
+		return item, nil
+	}
 	service, err = client.CoreV1().Services(namespace).Get(context.TODO(), serviceName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 Service 详情失败, %v\n", err))
@@ -41,24 +41,23 @@ func (s *statefulSet) fromCells(cells []DataCell) []appsv1.StatefulSet {
 
 // GetStatefulSets 获取StatefulSet列表，支持过滤，排序，分页，
 // client用于选择哪个集群
-func (s *statefulSet) GetStatefulSets(client *kubernetes.Clientset, filterName, namespace string, limit, page int) (statefulSetResp *StatefulSetResp, err error) {
+func (s *statefulSet) GetStatefulSets(client *kubernetes.Clientset, namespace string, query *DataSelectQuery) (statefulSetResp *StatefulSetResp, err error) {
 	// context.TODO()用于声明一个空的context上下文，用于List方法内设置这个请求的超时（源码），这里的常用用法
 	//metav1.ListOptions{}用于过滤List数据，如使用label，field等
-	stsList, err := client.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("获取 StatefulSet 列表失败, %v\n", err))
-		return nil, errors.New(fmt.Sprintf("获取 StatefulSet 列表失败, %v\n", err))
+	// 优先从 informer 缓存读取，缓存未同步时请求 API Server
+	items, ok := cacheList[appsv1.StatefulSet](K8s.cache(client), "statefulsets", namespace)
+	if !ok {
+		stsList, err := client.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			zap.L().Error(fmt.Sprintf("获取 StatefulSet 列表失败, %v\n", err))
+			return nil, errors.New(fmt.Sprintf("获取 StatefulSet 列表失败, %v\n", err))
+		}
+		items = stsList.Items
 	}
 	//实例化dataSelector对象，把 d 结构体中获取到的 StatefulSet 列表转化为 dataSelector 结构体，方便使用 dataSelector 结构体中 过滤，排序，分页功能
 	selectableData := &dataSelector{
-		GenericDataList: s.toCells(stsList.Items),
-		dataSelectQuery: &DataSelectQuery{
-			FilterQuery: &FilterQuery{Name: filterName},
-			PaginateQuery: &PaginateQuery{
-				Limit: limit,
-				Page:  page,
-			},
-		},
+		GenericDataList: s.toCells(items),
+		dataSelectQuery: query,
 	}
 	//先过滤，filtered中的数据才是总数据，data中的数据是排序分页后的数据，可能每次只有10行
 	filtered := selectableData.Filter()
@@ -76,6 +75,9 @@ func (s *statefulSet) GetStatefulSets(client *kubernetes.Clientset, filterName,
 
 // GetStatefulSetDetail 获取 StatefulSet 详情
 func (s *statefulSet) GetStatefulSetDetail(client *kubernetes.Clientset, stsName, namespace string) (sts *appsv1.StatefulSet, err error) {
+	if item, ok := cacheGet[appsv1.StatefulSet](K8s.cache(client), "statefulsets", namespace, stsName); ok {
+		return item, nil
+	}
 	stsDetail, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), stsName, metav1.GetOptions{})
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("获取 StatefulSet 详情失败, %v\n", err))
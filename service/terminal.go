@@ -1,17 +1,28 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"kubea/dao"
+	"kubea/middle/snowflake"
+	"kubea/model"
+	"kubea/settings"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 	"k8s.io/kubectl/pkg/scheme"
 )
 
@@ -19,37 +30,87 @@ var Terminal terminal
 
 type terminal struct{}
 
+const (
+	// terminalHeartbeatTimeout 客户端发送过 ping 后，超过该时间未收到任何消息视为断开
+	terminalHeartbeatTimeout = 90 * time.Second
+	// terminalCheckInterval 检查空闲、心跳及会话时长的间隔
+	terminalCheckInterval = 5 * time.Second
+)
+
+// terminalShells 未指定命令时依次尝试的 shell，兼容 alpine、busybox 等镜像
+var terminalShells = [][]string{{"/bin/bash"}, {"/bin/sh"}, {"/bin/ash"}}
+
 // TerminalSession 定义TerminalSession结构体
 // wsConn是websocket连接
 // sizeChan用来定义终端的宽和高
 // doneChan用于标记退出终端
+// recorder用于录制会话
+// stdinChan为客户端的输入，由 readLoop 分发，exec 失败回退到其他 shell 时不会丢失后续输入
 type TerminalSession struct {
-	wsConn   *websocket.Conn
-	sizeChan chan remotecommand.TerminalSize
-	doneChan chan struct{}
+	wsConn    *websocket.Conn
+	sizeChan  chan remotecommand.TerminalSize
+	doneChan  chan struct{}
+	recorder  *recorder
+	stdinChan chan string
+	pending   string
+	// gorilla websocket 不支持并发写
+	writeMu  sync.Mutex
+	doneOnce sync.Once
+	// 当前 exec 是否已有输出，用于区分命令未能启动与 shell 正常退出
+	output atomic.Bool
+
+	// 最近一次输入、最近一次收到消息的时间及是否发送过 ping，用于空闲超时及心跳检测
+	mu       sync.Mutex
+	lastIn   time.Time
+	lastSeen time.Time
+	pinged   bool
+	// 服务端主动断开的原因
+	reason string
 }
 
 // TerminalMessage 定义终端交互的内容格式，这个内容格式要遵循remotecommand规范
-// Operation用于定义操作类型
+// Operation用于定义操作类型，客户端发送 stdin、resize、ping，服务端返回 stdout、pong、exit
 // Data是具体的数据内容
 // Rows和Cols也就是终端的行数和列数，也就是宽和高，组成sizeChan
+// ExitCode为进程退出码，只在 exit 消息中返回
 type TerminalMessage struct {
 	Operation string `json:"operation"`
 	Data      string `json:"data"`
 	Rows      uint16 `json:"rows"`
 	Cols      uint16 `json:"cols"`
+	ExitCode  *int   `json:"exit_code,omitempty"`
 }
 
 // 处理websocket的协议升级
 var upgrader = func() websocket.Upgrader {
 	upgrader := websocket.Upgrader{}
 	upgrader.HandshakeTimeout = time.Second * 2
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		return true
-	}
+	upgrader.CheckOrigin = checkOrigin
 	return upgrader
 }()
 
+// checkOrigin 只允许配置的页面地址或与 ws 服务同主机的页面连接，端口可以不同
+// 非浏览器客户端不携带 Origin，仍需通过 token 校验
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if conf := settings.Conf.Terminal; conf != nil {
+		for _, item := range conf.AllowedOrigins {
+			if strings.EqualFold(strings.TrimSuffix(item, "/"), origin) {
+				return true
+			}
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), (&url.URL{Host: r.Host}).Hostname())
+}
+
 // NewTerminalSession 负责new一个TerminalSession实例，用于接管输入输出和升级ws协议
 // 工厂模式
 func NewTerminalSession(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*TerminalSession, error) {
@@ -57,17 +118,24 @@ func NewTerminalSession(w http.ResponseWriter, r *http.Request, responseHeader h
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
 	session := &TerminalSession{
-		wsConn:   conn,
-		sizeChan: make(chan remotecommand.TerminalSize),
-		doneChan: make(chan struct{}),
+		wsConn:    conn,
+		sizeChan:  make(chan remotecommand.TerminalSize, 8),
+		doneChan:  make(chan struct{}),
+		stdinChan: make(chan string, 64),
+		lastIn:    now,
+		lastSeen:  now,
 	}
+	go session.readLoop()
 	return session, nil
 }
 
-// Done 关闭doneChan，关闭后触发退出终端
+// Done 关闭doneChan，关闭后触发退出终端，可重复调用
 func (t *TerminalSession) Done() {
-	close(t.doneChan)
+	t.doneOnce.Do(func() {
+		close(t.doneChan)
+	})
 }
 
 // Next 定义调整终端的尺寸或退出终端
@@ -82,80 +150,207 @@ func (t *TerminalSession) Next() *remotecommand.TerminalSize {
 
 // Read 重写输入,输入的对象是web终端，接收web终端输入的内容
 func (t *TerminalSession) Read(p []byte) (int, error) {
-	_, message, err := t.wsConn.ReadMessage()
-	if err != nil {
-		zap.L().Error(fmt.Sprintf("read message err: %v\n", err))
-		return 0, err
-	}
-	var msg TerminalMessage
-	if err = json.Unmarshal([]byte(message), &msg); err != nil {
-		zap.L().Error(fmt.Sprintf("read parse message err: %v\n", err))
-		return 0, err
+	if t.pending == "" {
+		select {
+		case data := <-t.stdinChan:
+			t.pending = data
+		case <-t.doneChan:
+			return 0, io.EOF
+		}
 	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
 
-	switch msg.Operation {
-	case "stdin":
-		return copy(p, msg.Data), nil
-	case "resize":
-		t.sizeChan <- remotecommand.TerminalSize{
-			Width:  msg.Cols,
-			Height: msg.Rows,
+// readLoop 读取web终端的消息并分发，连接断开时退出终端
+func (t *TerminalSession) readLoop() {
+	defer t.Done()
+	for {
+		_, message, err := t.wsConn.ReadMessage()
+		if err != nil {
+			zap.L().Info(fmt.Sprintf("read message err: %v\n", err))
+			return
+		}
+		var msg TerminalMessage
+		if err = json.Unmarshal(message, &msg); err != nil {
+			zap.L().Error(fmt.Sprintf("read parse message err: %v\n", err))
+			return
 		}
-		return 0, nil
-	case "ping":
-		return 0, nil
-	default:
-		zap.L().Error(fmt.Sprintf("unknown message type %s\n", msg.Operation))
-		return 0, errors.New(fmt.Sprintf("unknown message type %s\n", msg.Operation))
-	}
 
+		t.mu.Lock()
+		t.lastSeen = time.Now()
+		t.mu.Unlock()
+
+		switch msg.Operation {
+		case "stdin":
+			t.mu.Lock()
+			t.lastIn = t.lastSeen
+			t.mu.Unlock()
+			select {
+			case t.stdinChan <- msg.Data:
+			case <-t.doneChan:
+				return
+			}
+		case "resize":
+			if t.recorder != nil {
+				t.recorder.resize(msg.Cols, msg.Rows)
+			}
+			// exec 未开始或正在回退时丢弃，前端会在下次调整时重新发送
+			select {
+			case t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}:
+			default:
+			}
+		case "ping":
+			t.mu.Lock()
+			t.pinged = true
+			t.mu.Unlock()
+			_ = t.send(TerminalMessage{Operation: "pong"})
+		default:
+			zap.L().Error(fmt.Sprintf("unknown message type %s\n", msg.Operation))
+		}
+	}
 }
 
 // Write 重写输出，接收web端的指令后，将结果返回出去
 func (t *TerminalSession) Write(p []byte) (int, error) {
-	msg, err := json.Marshal(TerminalMessage{
+	t.output.Store(true)
+	if t.recorder != nil {
+		t.recorder.output(string(p))
+	}
+	if err := t.send(TerminalMessage{
 		Operation: "stdout",
 		Data:      string(p),
-	})
+	}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
 
+// send 发送一条消息
+func (t *TerminalSession) send(msg TerminalMessage) error {
+	data, err := json.Marshal(msg)
 	if err != nil {
 		zap.L().Error(fmt.Sprintf("write parse message err: %v\n", err))
-		return 0, err
+		return err
 	}
 
-	if err := t.wsConn.WriteMessage(websocket.TextMessage, msg); err != nil {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if err := t.wsConn.WriteMessage(websocket.TextMessage, data); err != nil {
 		zap.L().Error(fmt.Sprintf("write message err: %v\n", err))
-		return 0, err
+		return err
 	}
-	return len(p), nil
+	return nil
+}
+
+// Exit 通知web终端进程已退出
+func (t *TerminalSession) Exit(code int, message string) {
+	_ = t.send(TerminalMessage{
+		Operation: "exit",
+		Data:      message,
+		ExitCode:  &code,
+	})
 }
 
 // Close 用于关闭websocket连接
 func (t *TerminalSession) Close() error {
+	t.Done()
 	return t.wsConn.Close()
 }
 
+// monitor 空闲超时、心跳超时或会话结束时取消 exec，并记录断开原因
+func (t *TerminalSession) monitor(ctx context.Context, cancel context.CancelFunc, idleTimeout time.Duration) {
+	ticker := time.NewTicker(terminalCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.doneChan:
+			cancel()
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			idle := time.Since(t.lastIn)
+			lost := t.pinged && time.Since(t.lastSeen) > terminalHeartbeatTimeout
+			t.mu.Unlock()
+			if idleTimeout > 0 && idle > idleTimeout {
+				t.stop(cancel, fmt.Sprintf("会话空闲超过 %s，已断开", idleTimeout))
+				return
+			}
+			if lost {
+				t.stop(cancel, "心跳超时，已断开")
+				return
+			}
+		}
+	}
+}
+
+// stop 记录断开原因并取消 exec
+func (t *TerminalSession) stop(cancel context.CancelFunc, reason string) {
+	t.mu.Lock()
+	t.reason = reason
+	t.mu.Unlock()
+	cancel()
+}
+
+// stopReason 服务端主动断开的原因，超过会话最长时间时 ctx 超时
+func (t *TerminalSession) stopReason(ctx context.Context) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reason == "" && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "会话超过最长时间，已断开"
+	}
+	return t.reason
+}
+
 // WsHandler 定义ws接口要做的事情
+// websocket 无法设置请求头，token 通过 ?token= 传递，需要拥有集群名称空间的写权限
+// command 为空时依次尝试 bash、sh、ash，指定时以空格分隔参数
 func (t *terminal) WsHandler(w http.ResponseWriter, r *http.Request) {
 	//解析form入参，其实就是GET请求，获取相关参数
 	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	namespace := r.Form.Get("namespace")
 	podName := r.Form.Get("pod_name")
 	containerName := r.Form.Get("container_name")
 	cluster := r.Form.Get("cluster")
-	zap.L().Info(fmt.Sprintf("exec pod: %s, container: %s, namespace: %s, cluster: %s \n", podName, containerName, namespace, cluster))
+	commands := terminalShells
+	if command := strings.Fields(r.Form.Get("command")); len(command) > 0 {
+		commands = [][]string{command}
+	}
+
+	//校验token及集群名称空间权限，失败时不升级协议
+	claims, err := Login.Verify(r.Form.Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	allowed, err := Scope.Check(claims.Role, cluster, namespace, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("无权限进入集群 %s 名称空间 %s 的容器", cluster, namespace), http.StatusForbidden)
+		return
+	}
+	zap.L().Info(fmt.Sprintf("exec pod: %s, container: %s, namespace: %s, cluster: %s, user: %s \n", podName, containerName, namespace, cluster, claims.Username))
 
 	//获取集群的client
 	client, err := K8s.GetClient(cluster)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	//加载k8s配置
-	conf, err := clientcmd.BuildConfigFromFlags("", K8s.KubeConfMap[cluster])
+	conf, err := K8s.GetConfig(cluster)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -166,49 +361,174 @@ func (t *terminal) WsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//记录会话并录像，录像失败不影响使用
+	now := time.Now()
+	record := &model.TerminalRecord{
+		ID:        snowflake.GenID(),
+		UserName:  claims.Username,
+		Cluster:   cluster,
+		Namespace: namespace,
+		Pod:       podName,
+		Container: containerName,
+		Command:   strings.Join(commands[0], " "),
+		ClientIP:  clientIP(r),
+		StartedAt: &now,
+	}
+	record.FileName = recordFileName(record.ID)
+	if err := dao.Terminal.Add(record); err != nil {
+		record = nil
+	} else if pty.recorder, err = newRecorder(recordPath(record.FileName), fmt.Sprintf("%s@%s/%s/%s/%s", claims.Username, cluster, namespace, podName, containerName)); err != nil {
+		zap.L().Error("创建终端录像失败", zap.Error(err))
+	}
+
 	//处理关闭
 	defer func() {
 		zap.L().Info("close session.")
 		pty.Close()
+		if record == nil {
+			return
+		}
+		var size int64
+		if pty.recorder != nil {
+			size = pty.recorder.close()
+		}
+		endedAt := time.Now()
+		_ = dao.Terminal.Finish(record.ID, record.Command, endedAt, int64(endedAt.Sub(now).Seconds()), size)
 	}()
 
-	req := client.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(namespace).
-		SubResource("exec").
-		VersionedParams(&v1.PodExecOptions{
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       true,
-			Container: containerName,
-			Command:   []string{"/bin/bash"},
-		}, scheme.ParameterCodec)
-
-	msg, _ := json.Marshal(req.URL())
-	zap.L().Info(string(msg))
-
-	executor, err := remotecommand.NewSPDYExecutor(conf, "POST", req.URL())
+	//超过最长时间、空闲或心跳超时后取消 exec
+	idleTimeout, maxSession := terminalTimeouts()
+	ctx, cancel := context.WithCancel(context.Background())
+	if maxSession > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), maxSession)
+	}
+	defer cancel()
+	go pty.monitor(ctx, cancel, idleTimeout)
 
-	if err != nil {
+	for i, command := range commands {
+		if record != nil {
+			record.Command = strings.Join(command, " ")
+		}
+		req := client.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Name(podName).
+			Namespace(namespace).
+			SubResource("exec").
+			VersionedParams(&v1.PodExecOptions{
+				Stdin:     true,
+				Stdout:    true,
+				Stderr:    true,
+				TTY:       true,
+				Container: containerName,
+				Command:   command,
+			}, scheme.ParameterCodec)
+
+		msg, _ := json.Marshal(req.URL())
+		zap.L().Info(string(msg))
+
+		executor, err := remotecommand.NewSPDYExecutor(conf, "POST", req.URL())
+		if err != nil {
+			pty.Exit(-1, fmt.Sprintf("Exec to pod error: %v", err))
+			return
+		}
+
+		pty.output.Store(false)
+		err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdin:             pty,
+			Stdout:            pty,
+			Stderr:            pty,
+			Tty:               true,
+			TerminalSizeQueue: pty,
+		})
+
+		//shell 不存在、未能启动时尝试下一个，已有输出说明 shell 已启动，不再回退
+		if i < len(commands)-1 && !pty.output.Load() && commandNotFound(err) {
+			zap.L().Info(fmt.Sprintf("%s 不存在，尝试下一个 shell", command[0]))
+			continue
+		}
+
+		if reason := pty.stopReason(ctx); reason != "" {
+			pty.Write([]byte("\r\n" + reason + "\r\n"))
+		}
+		var exitErr utilexec.ExitError
+		switch {
+		case err == nil:
+			pty.Exit(0, fmt.Sprintf("%s 已退出, exit code 0", command[0]))
+		case errors.As(err, &exitErr):
+			pty.Exit(exitErr.ExitStatus(), fmt.Sprintf("%s 已退出, exit code %d", command[0], exitErr.ExitStatus()))
+		default:
+			msg := fmt.Sprintf("Exec to pod error: %v \n", err)
+			zap.L().Error(msg)
+			//将报错发送给web终端，给用户看
+			pty.Write([]byte(msg))
+			pty.Exit(-1, msg)
+		}
 		return
 	}
+}
 
-	err = executor.Stream(remotecommand.StreamOptions{
-		Stdin:             pty,
-		Stdout:            pty,
-		Stderr:            pty,
-		Tty:               true,
-		TerminalSizeQueue: pty,
-	})
+// commandNotFound 容器运行时因命令不存在未能启动 exec，报 executable file not found 或 stat 失败
+// 只看退出码无法区分，shell 中最后一条命令不存在时退出码同样为 127
+func commandNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "executable file not found") || strings.Contains(msg, "no such file or directory")
+}
+
+// terminalTimeouts 空闲超时及会话最长时间，未配置时分别为 30 分钟、4 小时，配置为 0 时不限制
+func terminalTimeouts() (time.Duration, time.Duration) {
+	conf := settings.Conf.Terminal
+	if conf == nil {
+		return 30 * time.Minute, 4 * time.Hour
+	}
+	return time.Duration(conf.IdleTimeout) * time.Second, time.Duration(conf.MaxSession) * time.Second
+}
 
+// clientIP 客户端地址，与 gin 的 ClientIP 一致，连接来自 trusted_proxies 中的代理时才取请求头
+// X-Forwarded-For 从右向左跳过可信代理，取第一个不可信的地址
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
 	if err != nil {
-		msg := fmt.Sprintf("Exec to pod error: %v \n", err)
-		zap.L().Error(msg)
-		//将报错发送给web终端，给用户看
-		pty.Write([]byte(msg))
-		//触发websocket的关闭
-		pty.Done()
+		host = r.RemoteAddr
+	}
+	if !trustedProxy(host) {
+		return host
+	}
+
+	items := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(items[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+		if i == 0 || !trustedProxy(ip) {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return host
+}
+
+// trustedProxy 地址是否为 trusted_proxies 中的代理，配置项为 IP 或 CIDR
+func trustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, item := range settings.Conf.TrustedProxies {
+		if !strings.Contains(item, "/") {
+			if proxy := net.ParseIP(item); proxy != nil && proxy.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(item); err == nil && cidr.Contains(ip) {
+			return true
+		}
 	}
+	return false
 }
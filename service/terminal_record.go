@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"kubea/settings"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// recorder 以 asciinema v2 格式录制终端输出，第一条事件前写入文件头，尺寸取自第一次 resize
+type recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	title   string
+	start   time.Time
+	started bool
+	width   uint16
+	height  uint16
+	size    int64
+}
+
+// newRecorder 创建录像文件
+func newRecorder(path, title string) (*recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("创建录像目录失败, %v", err))
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("创建录像文件失败, %v", err))
+	}
+	return &recorder{
+		file:   file,
+		title:  title,
+		start:  time.Now(),
+		width:  80,
+		height: 24,
+	}, nil
+}
+
+// resize 记录终端尺寸变化
+func (r *recorder) resize(width, height uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		r.width, r.height = width, height
+		return
+	}
+	r.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// output 记录终端输出
+func (r *recorder) output(data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event("o", data)
+}
+
+// event 写入一条事件：[时间偏移(秒), 类型, 数据]
+func (r *recorder) event(kind, data string) {
+	if r.file == nil {
+		return
+	}
+	if !r.started {
+		r.started = true
+		r.write(map[string]interface{}{
+			"version":   2,
+			"width":     r.width,
+			"height":    r.height,
+			"timestamp": r.start.Unix(),
+			"title":     r.title,
+			"env":       map[string]string{"TERM": "xterm"},
+		})
+	}
+	r.write([]interface{}{time.Since(r.start).Seconds(), kind, data})
+}
+
+// write 写入一行 JSON
+func (r *recorder) write(v interface{}) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	n, err := r.file.Write(append(line, '\n'))
+	r.size += int64(n)
+	if err != nil {
+		zap.L().Error("写入终端录像失败", zap.Error(err))
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// close 关闭录像文件，返回文件大小
+func (r *recorder) close() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.size
+}
+
+// Records 终端会话记录
+func (*terminal) Records(q *dao.TerminalQuery) (*dao.TerminalRecords, error) {
+	return dao.Terminal.List(q)
+}
+
+// RecordFile 返回会话记录及录像文件路径，用于回放
+func (*terminal) RecordFile(id int64) (*model.TerminalRecord, string, error) {
+	data, has, err := dao.Terminal.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !has {
+		return nil, "", errors.New("查询无此终端会话")
+	}
+	path := recordPath(data.FileName)
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", errors.New("录像文件不存在")
+	}
+	return data, path, nil
+}
+
+// recordFileName 录像文件名
+func recordFileName(id int64) string {
+	return strconv.FormatInt(id, 10) + ".cast"
+}
+
+// recordPath 录像文件路径，只取文件名，避免路径穿越
+func recordPath(fileName string) string {
+	dir := "./records"
+	if conf := settings.Conf.Terminal; conf != nil && conf.RecordPath != "" {
+		dir = conf.RecordPath
+	}
+	return filepath.Join(dir, filepath.Base(fileName))
+}
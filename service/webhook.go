@@ -0,0 +1,160 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"kubea/dao"
+	"kubea/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var Webhook webhook
+
+type webhook struct{}
+
+// webhookSignatureTTL 签名时间戳的有效期
+const webhookSignatureTTL = 5 * time.Minute
+
+// webhookNames 支持回调的集成，启动时自动生成密钥
+var webhookNames = []string{model.WebhookJenkins, model.WebhookGitlab}
+
+// Init 为尚未配置的集成生成密钥
+func (*webhook) Init() error {
+	for _, name := range webhookNames {
+		_, has, err := dao.Webhook.Has(name)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		if err := dao.Webhook.Add(&model.Webhook{Name: name, Secret: secret, Enabled: true}); err != nil {
+			return err
+		}
+		zap.L().Info(fmt.Sprintf("已生成 %s webhook 密钥，需在 webhook 管理中轮换后获取", name))
+	}
+	return nil
+}
+
+// List 返回所有集成，不含密钥
+func (*webhook) List() ([]*model.Webhook, error) {
+	return dao.Webhook.GetAll()
+}
+
+// Update 启用、停用集成，修改描述
+func (*webhook) Update(name string, enabled bool, description string) error {
+	data, err := getWebhook(name)
+	if err != nil {
+		return err
+	}
+	data.Enabled = enabled
+	data.Description = description
+	return dao.Webhook.Save(data)
+}
+
+// Rotate 轮换密钥，旧密钥在 grace 秒内仍然有效，便于更新调用方的配置
+// 返回新密钥，密钥只在此时返回一次
+func (*webhook) Rotate(name string, grace int) (*model.Webhook, string, error) {
+	data, err := getWebhook(name)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	data.PrevSecret = ""
+	data.PrevExpiresAt = nil
+	if grace > 0 {
+		expiresAt := now.Add(time.Duration(grace) * time.Second)
+		data.PrevSecret = data.Secret
+		data.PrevExpiresAt = &expiresAt
+	}
+	data.Secret = secret
+	data.RotatedAt = &now
+	if err := dao.Webhook.Save(data); err != nil {
+		return nil, "", err
+	}
+	return data, secret, nil
+}
+
+// Verify 校验回调请求，token 为明文密钥，signature 为 "<timestamp>.<请求体>" 的 HMAC-SHA256，形如 sha256=<hex>，二者满足其一即可
+// timestamp 为签名时的 unix 秒，与当前时间相差超过 webhookSignatureTTL 时拒绝，防止重放
+func (*webhook) Verify(name, token, signature, timestamp string, body []byte) error {
+	data, err := getWebhook(name)
+	if err != nil {
+		return err
+	}
+	if !data.Enabled {
+		return errors.New(fmt.Sprintf("%s webhook 已停用", name))
+	}
+
+	// 签名需携带有效期内的时间戳
+	var stale error
+	if signature != "" {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			stale = errors.New(fmt.Sprintf("%s webhook 签名缺少有效的时间戳", name))
+		} else if age := time.Since(time.Unix(ts, 0)); age > webhookSignatureTTL || age < -webhookSignatureTTL {
+			stale = errors.New(fmt.Sprintf("%s webhook 签名已过期", name))
+		}
+	}
+
+	secrets := []string{data.Secret}
+	if data.PrevSecret != "" && data.PrevExpiresAt != nil && time.Now().Before(*data.PrevExpiresAt) {
+		secrets = append(secrets, data.PrevSecret)
+	}
+	for _, secret := range secrets {
+		if token != "" && hmac.Equal([]byte(token), []byte(secret)) {
+			return nil
+		}
+		if signature != "" && stale == nil {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(timestamp + "."))
+			mac.Write(body)
+			expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+				return nil
+			}
+		}
+	}
+	if stale != nil {
+		return stale
+	}
+	return errors.New(fmt.Sprintf("%s webhook 签名校验失败", name))
+}
+
+// getWebhook 根据名称查询集成
+func getWebhook(name string) (*model.Webhook, error) {
+	data, has, err := dao.Webhook.Has(name)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New(fmt.Sprintf("%s webhook 不存在", name))
+	}
+	return data, nil
+}
+
+// newWebhookSecret 生成随机密钥
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		zap.L().Error("生成webhook密钥失败," + err.Error())
+		return "", errors.New("生成webhook密钥失败," + err.Error())
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -20,9 +20,25 @@ type AppConfig struct {
 	WsPort         int    `mapstructure:"ws_port"`
 	PodLogTailLine int    `mapstructure:"pod_log_tail_line"`
 	UploadPath     string `mapstructure:"upload_path"`
+	ChartMaxSize   int    `mapstructure:"chart_max_size"`  // 上传chart文件大小上限，单位 MB
+	AuditRetention int    `mapstructure:"audit_retention"` // 审计日志保留天数，0 为不清理
+	AuditKey       string `mapstructure:"audit_key"`       // 审计日志请求体摘要的 HMAC 密钥，为空时不记录摘要
+	// 反向代理的 IP 或 CIDR，只信任来自这些地址的 X-Forwarded-For、X-Real-Ip
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 	*Admin         `mapstructure:"admin"`
+	*JWT           `mapstructure:"jwt"`
 	*LogConfig     `mapstructure:"log"`
-	*KubeConfigs   `mapstructure:"kube_configs"`
+	// 集群在数据库中维护，kube_configs 仅用于首次导入
+	KubeConfigs map[string]string `mapstructure:"kube_configs"`
+	// 集群健康探测间隔，单位秒
+	ClusterProbeInterval int `mapstructure:"cluster_probe_interval"`
+	// 远程 chart 仓库 index 缓存目录及刷新间隔，单位秒
+	HelmRepoCache   string `mapstructure:"helm_repo_cache"`
+	HelmRepoRefresh int    `mapstructure:"helm_repo_refresh"`
+	// web 终端
+	*Terminal `mapstructure:"terminal"`
+	// 内置chart仓库的访问账号
+	*ChartRepo `mapstructure:"chart_repo"`
 
 	*MySQLConfig `mapstructure:"mysql"`
 	//*RedisConfig `mapstructure:"redis"`
@@ -31,11 +47,27 @@ type AppConfig struct {
 	*GitLab `mapstructure:"gitlab"`
 }
 
+// ChartRepo 内置chart仓库 /charts 的 basic auth 账号，Public 为 true 时允许匿名访问
+type ChartRepo struct {
+	Public   bool   `mapstructure:"public"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
 type Admin struct {
 	UserName string `mapstructure:"username"`
 	PassWord string `mapstructure:"password"`
 }
 
+type JWT struct {
+	Secret        string `mapstructure:"secret"`
+	PrivateKey    string `mapstructure:"private_key"`
+	PublicKey     string `mapstructure:"public_key"`
+	Issuer        string `mapstructure:"issuer"`
+	AccessExpire  int    `mapstructure:"access_expire"`
+	RefreshExpire int    `mapstructure:"refresh_expire"`
+}
+
 type LogConfig struct {
 	Level      string `mapstructure:"level"`
 	Filename   string `mapstructure:"filename"`
@@ -44,11 +76,6 @@ type LogConfig struct {
 	MaxBackups int    `mapstructure:"max_backups"`
 }
 
-type KubeConfigs struct {
-	DEV string `mapstructure:"dev"`
-	TST string `mapstructure:"tst"`
-}
-
 type MySQLConfig struct {
 	Host         string `mapstructure:"host"`
 	User         string `mapstructure:"user"`
@@ -78,6 +105,16 @@ type CiCd struct {
 	CocosUserPassword string `mapstructure:"cocos_user_password"`
 }
 
+type Terminal struct {
+	// 允许连接终端的页面地址，如 https://kubea.example.com，为空时只允许与 ws 服务同主机的页面
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// 会话录像(asciinema)保存目录
+	RecordPath string `mapstructure:"record_path"`
+	// 空闲超时及会话最长时间，单位秒，0 为不限制
+	IdleTimeout int `mapstructure:"idle_timeout"`
+	MaxSession  int `mapstructure:"max_session"`
+}
+
 type GitLab struct {
 	GitLabUrl   string `mapstructure:"gitlab_url"`
 	GitLabToken string `mapstructure:"gitlab_token"`
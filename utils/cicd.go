@@ -21,7 +21,7 @@ func CiCd(tmpUrl, auth string) (body []byte, err error) {
 
 	if err != nil {
 		zap.L().Error("New HTTP 报错: ", zap.Error(err))
-		return nil, errors.New(fmt.Sprintf("New HTTP 请求报错: ", err.Error()))
+		return nil, errors.New(fmt.Sprintf("New HTTP 请求报错: %v", err))
 	}
 
 	// 添加 Basic Authentication 头
@@ -35,14 +35,14 @@ func CiCd(tmpUrl, auth string) (body []byte, err error) {
 	res, err := client.Do(req)
 	if err != nil {
 		zap.L().Error("HTTP 请求报错: ", zap.Error(err))
-		return nil, errors.New(fmt.Sprintf("HTTP 请求报错: ", err.Error()))
+		return nil, errors.New(fmt.Sprintf("HTTP 请求报错: %v", err))
 	}
 	defer res.Body.Close()
 
 	body, err = io.ReadAll(res.Body)
 	if err != nil {
 		zap.L().Error("IO 数据解析报错: ", zap.Error(err))
-		return nil, errors.New(fmt.Sprintf("IO 数据解析报错: ", err.Error()))
+		return nil, errors.New(fmt.Sprintf("IO 数据解析报错: %v", err))
 	}
 
 	return body, nil
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron 标准 5 段 cron 表达式：分 时 日 月 周，支持 *、a-b、a,b、*/n、a-b/n
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// 日、周都有限制时按 cron 惯例取并集，以 * 开头(含 */n)视为不限制
+	domStar, dowStar bool
+}
+
+// cronBounds 各字段的取值范围
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// ParseCron 解析 cron 表达式，周日可以写 0 或 7
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New(fmt.Sprintf("cron 表达式 %s 格式错误，需要 5 段：分 时 日 月 周", expr))
+	}
+
+	bits := make([]uint64, 5)
+	for i, field := range fields {
+		value, err := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("cron 表达式 %s 格式错误, %v", expr, err))
+		}
+		bits[i] = value
+	}
+	// 7 与 0 都表示周日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// Match 判断时间是否匹配，精确到分钟
+func (c *Cron) Match(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Last 返回 [from, to] 之间最近一次匹配的时间
+func (c *Cron) Last(from, to time.Time) (time.Time, bool) {
+	for t := to.Truncate(time.Minute); !t.Before(from.Truncate(time.Minute)); t = t.Add(-time.Minute) {
+		if c.Match(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseCronField 解析单个字段，返回取值的位图
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.New(fmt.Sprintf("步长 %s 错误", part[i+1:]))
+			}
+			step, stepped = n, true
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New(fmt.Sprintf("取值 %s 错误", part))
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, errors.New(fmt.Sprintf("取值 %s 错误", part))
+				}
+			} else if stepped {
+				// a/n 表示从 a 开始到最大值
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, errors.New(fmt.Sprintf("取值 %s 超出范围 %d-%d", part, min, max))
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
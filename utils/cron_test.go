@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// 2026-06: 1 日为周一，6 日为周六，7 日为周日
+func cronTime(day, hour, minute int) time.Time {
+	return time.Date(2026, 6, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestCronMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		at   time.Time
+		want bool
+	}{
+		// 日、周都有限制时取并集
+		{"日周并集-按日", "0 9 15 * 5", cronTime(15, 9, 0), true},
+		{"日周并集-按周", "0 9 15 * 5", cronTime(5, 9, 0), true},
+		{"日周并集-都不匹配", "0 9 15 * 5", cronTime(2, 9, 0), false},
+		// 日或周为 * 时取交集
+		{"日为星号-匹配周", "0 9 * * 1", cronTime(8, 9, 0), true},
+		{"日为星号-不匹配周", "0 9 * * 1", cronTime(2, 9, 0), false},
+		{"周为星号-匹配日", "0 9 15 * *", cronTime(15, 9, 0), true},
+		{"周为星号-不匹配日", "0 9 15 * *", cronTime(16, 9, 0), false},
+		// */n 同样视为不限制，取交集
+		{"日为步长-都匹配", "0 9 */2 * 1", cronTime(15, 9, 0), true},
+		{"日为步长-只匹配周", "0 9 */2 * 1", cronTime(8, 9, 0), false},
+		{"日为步长-只匹配日", "0 9 */2 * 1", cronTime(3, 9, 0), false},
+		// */n
+		{"星号步长-匹配", "*/15 * * * *", cronTime(2, 10, 30), true},
+		{"星号步长-不匹配", "*/15 * * * *", cronTime(2, 10, 31), false},
+		// a/n 从 a 开始到最大值
+		{"起始步长-起点", "5/20 * * * *", cronTime(2, 10, 5), true},
+		{"起始步长-匹配", "5/20 * * * *", cronTime(2, 10, 45), true},
+		{"起始步长-起点之前", "5/20 * * * *", cronTime(2, 10, 0), false},
+		{"起始步长为1-到最大值", "10/1 * * * *", cronTime(2, 10, 59), true},
+		{"起始步长为1-起点之前", "10/1 * * * *", cronTime(2, 10, 9), false},
+		{"范围步长", "0-30/10 * * * *", cronTime(2, 10, 20), true},
+		{"范围步长-超出范围", "0-30/10 * * * *", cronTime(2, 10, 40), false},
+		// 周日可以写 0 或 7
+		{"周日为7", "0 0 * * 7", cronTime(7, 0, 0), true},
+		{"周日为7-周六", "0 0 * * 7", cronTime(6, 0, 0), false},
+		{"周日为0", "0 0 * * 0", cronTime(7, 0, 0), true},
+		{"周范围含7", "0 0 * * 5-7", cronTime(7, 0, 0), true},
+		// 列表及月份
+		{"列表", "0 8,20 * 6 *", cronTime(2, 20, 0), true},
+		{"月份不匹配", "0 8,20 * 7 *", cronTime(2, 20, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error: %v", tt.expr, err)
+			}
+			if got := cron.Match(tt.at); got != tt.want {
+				t.Errorf("ParseCron(%q).Match(%s) = %v, want %v", tt.expr, tt.at.Format("2006-01-02 15:04 Mon"), got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCronLast 按冻结窗口的用法，开始时间在 (at - duration, at] 之间时处于窗口内
+func TestCronLast(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		duration time.Duration
+		at       time.Time
+		want     time.Time
+		ok       bool
+	}{
+		{"跨零点-窗口内", "0 22 * * *", 4 * time.Hour, cronTime(2, 1, 30), cronTime(1, 22, 0), true},
+		{"跨零点-开始时刻", "0 22 * * *", 4 * time.Hour, cronTime(1, 22, 0), cronTime(1, 22, 0), true},
+		{"跨零点-结束时刻", "0 22 * * *", 4 * time.Hour, cronTime(2, 2, 0), time.Time{}, false},
+		{"跨零点-开始之前", "0 22 * * *", 4 * time.Hour, cronTime(2, 21, 59), time.Time{}, false},
+		{"跨周-周六开始周日仍在窗口内", "0 23 * * 6", 2 * time.Hour, cronTime(7, 0, 30), cronTime(6, 23, 0), true},
+		{"跨周-周日开始不匹配", "0 23 * * 6", 2 * time.Hour, cronTime(8, 0, 30), time.Time{}, false},
+		{"多次匹配取最近", "*/30 * * * *", time.Hour, cronTime(2, 10, 45), cronTime(2, 10, 30), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error: %v", tt.expr, err)
+			}
+			got, ok := cron.Last(tt.at.Add(-tt.duration).Add(time.Minute), tt.at)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("ParseCron(%q).Last at %s = %s, %v, want %s, %v", tt.expr, tt.at.Format("2006-01-02 15:04"), got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jenkins 通过 REST API 触发构建、查询队列和构建状态、读取控制台输出
+type Jenkins struct {
+	auth   string
+	client *http.Client
+}
+
+// JenkinsQueueItem 队列项，Executable 不为空时表示已开始构建
+type JenkinsQueueItem struct {
+	Cancelled  bool   `json:"cancelled"`
+	Why        string `json:"why"`
+	Executable *struct {
+		Number int    `json:"number"`
+		Url    string `json:"url"`
+	} `json:"executable"`
+}
+
+// JenkinsBuild 构建状态，Result 为 SUCCESS/FAILURE/ABORTED/UNSTABLE，构建中为空
+type JenkinsBuild struct {
+	Number   int    `json:"number"`
+	Url      string `json:"url"`
+	Building bool   `json:"building"`
+	Result   string `json:"result"`
+	Duration int64  `json:"duration"`
+}
+
+// NewJenkins auth 为 user:token
+func NewJenkins(auth string) *Jenkins {
+	return &Jenkins{
+		auth:   auth,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// BuildWithParameters 触发参数化构建，返回队列项地址
+func (j *Jenkins) BuildWithParameters(jobUrl string, params url.Values) (string, error) {
+	res, err := j.do(http.MethodPost, strings.TrimSuffix(jobUrl, "/")+"/buildWithParameters?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return "", errors.New(fmt.Sprintf("触发 Jenkins 构建失败: %s", res.Status))
+	}
+	return res.Header.Get("Location"), nil
+}
+
+// QueueItem 查询队列项
+func (j *Jenkins) QueueItem(queueUrl string) (*JenkinsQueueItem, error) {
+	data := new(JenkinsQueueItem)
+	if err := j.getJson(queueUrl, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Build 查询构建状态
+func (j *Jenkins) Build(buildUrl string) (*JenkinsBuild, error) {
+	data := new(JenkinsBuild)
+	if err := j.getJson(buildUrl, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ProgressiveText 从 start 开始读取控制台输出，返回内容、下次读取的位置以及是否还有输出
+func (j *Jenkins) ProgressiveText(buildUrl string, start int64) (string, int64, bool, error) {
+	res, err := j.do(http.MethodGet, fmt.Sprintf("%s/logText/progressiveText?start=%d", strings.TrimSuffix(buildUrl, "/"), start))
+	if err != nil {
+		return "", start, false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", start, false, errors.New(fmt.Sprintf("读取 Jenkins 控制台输出失败: %s", res.Status))
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", start, false, errors.New(fmt.Sprintf("IO 数据解析报错: %v", err))
+	}
+
+	next, err := strconv.ParseInt(res.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		next = start + int64(len(body))
+	}
+	return string(body), next, res.Header.Get("X-More-Data") == "true", nil
+}
+
+// Stop 中止构建
+func (j *Jenkins) Stop(buildUrl string) error {
+	res, err := j.do(http.MethodPost, strings.TrimSuffix(buildUrl, "/")+"/stop")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return errors.New(fmt.Sprintf("中止 Jenkins 构建失败: %s", res.Status))
+	}
+	return nil
+}
+
+// CancelQueue 取消排队中的构建，队列地址形如 <jenkins>/queue/item/<id>/
+func (j *Jenkins) CancelQueue(queueUrl string) error {
+	u, err := url.Parse(queueUrl)
+	if err != nil {
+		return errors.New(fmt.Sprintf("队列地址格式错误: %v", err))
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/queue/item/")
+	if i < 0 {
+		return errors.New(fmt.Sprintf("队列地址格式错误: %s", queueUrl))
+	}
+	u.Path = path[:i] + "/queue/cancelItem"
+	u.RawQuery = "id=" + path[i+len("/queue/item/"):]
+
+	res, err := j.do(http.MethodPost, u.String())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return errors.New(fmt.Sprintf("取消 Jenkins 队列失败: %s", res.Status))
+	}
+	return nil
+}
+
+// getJson 请求 <url>/api/json 并解析
+func (j *Jenkins) getJson(rawUrl string, data interface{}) error {
+	res, err := j.do(http.MethodGet, strings.TrimSuffix(rawUrl, "/")+"/api/json")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("请求 Jenkins 失败: %s", res.Status))
+	}
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		return errors.New(fmt.Sprintf("IO 数据解析报错: %v", err))
+	}
+	return nil
+}
+
+// do 发起带 Basic Authentication 的请求
+func (j *Jenkins) do(method, rawUrl string) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawUrl, nil)
+	if err != nil {
+		zap.L().Error("New HTTP 报错: ", zap.Error(err))
+		return nil, errors.New(fmt.Sprintf("New HTTP 请求报错: %v", err))
+	}
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(j.auth)))
+
+	res, err := j.client.Do(req)
+	if err != nil {
+		zap.L().Error("HTTP 请求报错: ", zap.Error(err))
+		return nil, errors.New(fmt.Sprintf("HTTP 请求报错: %v", err))
+	}
+	return res, nil
+}
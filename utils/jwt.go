@@ -1,30 +1,116 @@
 package utils
 
 import (
+	"crypto/rsa"
 	"errors"
 	"github.com/dgrijalva/jwt-go"
 	"go.uber.org/zap"
+	"kubea/middle/snowflake"
+	"kubea/settings"
+	"os"
+	"strconv"
+	"time"
 )
 
 var JWTToken jwtToken
 
-type jwtToken struct{}
+// jwtToken 配置了密钥对时，启动时加载的私钥及公钥，未配置时为空，使用 HS256
+type jwtToken struct {
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
 
-// 定义token反序列化后的内容，一般会放用户信息
+// token类型，refresh token只能用于换取新的access token
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// CustomClaims 定义token反序列化后的内容，一般会放用户信息
 type CustomClaims struct {
+	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Role     uint   `json:"role"`
+	Type     string `json:"type"`
 	jwt.StandardClaims
 }
 
-// 加解密因子
-const (
-	SECRET = "evescn"
-)
+// Init 加载JWT密钥对，private_key 和 public_key 需同时配置或同时为空
+func (j *jwtToken) Init() error {
+	cfg := settings.Conf.JWT
+	if cfg.PrivateKey == "" && cfg.PublicKey == "" {
+		return nil
+	}
+	if cfg.PrivateKey == "" || cfg.PublicKey == "" {
+		return errors.New("JWT private_key 和 public_key 需同时配置")
+	}
+
+	pem, err := os.ReadFile(cfg.PrivateKey)
+	if err != nil {
+		return errors.New("读取JWT私钥失败, " + err.Error())
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return errors.New("解析JWT私钥失败, " + err.Error())
+	}
+	if pem, err = os.ReadFile(cfg.PublicKey); err != nil {
+		return errors.New("读取JWT公钥失败, " + err.Error())
+	}
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+	if err != nil {
+		return errors.New("解析JWT公钥失败, " + err.Error())
+	}
+	if privateKey.PublicKey.N.Cmp(publicKey.N) != 0 || privateKey.PublicKey.E != publicKey.E {
+		return errors.New("JWT private_key 与 public_key 不匹配")
+	}
+
+	j.privateKey = privateKey
+	j.publicKey = publicKey
+	return nil
+}
+
+// GenToken 签发token，tokenType 为 AccessToken 或 RefreshToken
+func (j *jwtToken) GenToken(userID uint, username string, role uint, tokenType string) (string, *CustomClaims, error) {
+	cfg := settings.Conf.JWT
 
-func (*jwtToken) ParseToken(tokenString string) (claims *CustomClaims, err error) {
+	var expire time.Duration
+	if tokenType == RefreshToken {
+		expire = time.Duration(cfg.RefreshExpire) * time.Hour
+	} else {
+		expire = time.Duration(cfg.AccessExpire) * time.Minute
+	}
+
+	now := time.Now()
+	claims := &CustomClaims{
+		UserID:   userID,
+		Username: username,
+		Role:     role,
+		Type:     tokenType,
+		StandardClaims: jwt.StandardClaims{
+			Id:        strconv.FormatInt(snowflake.GenID(), 10),
+			Issuer:    cfg.Issuer,
+			Subject:   username,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(expire).Unix(),
+		},
+	}
+
+	method, key, err := j.signingKey()
+	if err != nil {
+		return "", nil, err
+	}
+	tokenString, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		zap.L().Error("sign token failed", zap.Error(err))
+		return "", nil, errors.New("签发Token失败, " + err.Error())
+	}
+	return tokenString, claims, nil
+}
+
+func (j *jwtToken) ParseToken(tokenString string) (claims *CustomClaims, err error) {
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SECRET), nil
+		return j.verifyKey(token)
 	})
 
 	if err != nil {
@@ -36,10 +122,9 @@ func (*jwtToken) ParseToken(tokenString string) (claims *CustomClaims, err error
 				return nil, errors.New("TokenExpired")
 			} else if ve.Errors&jwt.ValidationErrorNotValidYet != 0 {
 				return nil, errors.New("TokenNotValidYet")
-			} else {
-				return nil, errors.New("TokenInvalid")
 			}
 		}
+		return nil, errors.New("TokenInvalid")
 	}
 
 	//将Token对象中的Claims断言成CustomClaims
@@ -48,3 +133,26 @@ func (*jwtToken) ParseToken(tokenString string) (claims *CustomClaims, err error
 	}
 	return nil, errors.New("解析Token无效")
 }
+
+// signingKey 配置了密钥对则使用RS256，否则使用secret进行HS256签名
+func (j *jwtToken) signingKey() (jwt.SigningMethod, interface{}, error) {
+	if j.privateKey == nil {
+		return jwt.SigningMethodHS256, []byte(settings.Conf.JWT.Secret), nil
+	}
+	return jwt.SigningMethodRS256, j.privateKey, nil
+}
+
+// verifyKey 校验签名算法与配置一致，防止使用其他算法伪造token
+func (j *jwtToken) verifyKey(token *jwt.Token) (interface{}, error) {
+	if j.publicKey == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("签名算法不匹配")
+		}
+		return []byte(settings.Conf.JWT.Secret), nil
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, errors.New("签名算法不匹配")
+	}
+	return j.publicKey, nil
+}